@@ -1,6 +1,11 @@
 package ql
 
-import "github.com/mibk/ql/query"
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mibk/ql/query"
+)
 
 type direction bool
 
@@ -11,42 +16,171 @@ const (
 	Desc direction = true
 )
 
+// Ordering is a column to ORDER a statement by together with the direction.
+type Ordering struct {
+	Column string
+	Desc   bool
+}
+
+// Sort returns the Ordering by the column in the direction.
+func Sort(col string, dir direction) Ordering {
+	return Ordering{Column: col, Desc: dir == Desc}
+}
+
+// Orderer is an Ordering or a By map accepted by builders' Order methods.
+type Orderer interface {
+	orderings() []Ordering
+}
+
+func (o Ordering) orderings() []Ordering {
+	return []Ordering{o}
+}
+
 // By is a map of columns and order directions used in builders' Order methods.
-// Example of usage:
-//	b.Order(ql.By{"col1": ql.Asc,"col2": ql.Desc})
+// The columns are sorted by name, which determines their priority. To choose
+// the priority, pass orderings made by Sort instead. Example of usage:
+//
+//	b.Order(ql.By{"col1": ql.Asc, "col2": ql.Desc})
+//	b.Order(ql.Sort("col2", ql.Desc), ql.Sort("col1", ql.Asc))
 type By map[string]direction
 
+func (by By) orderings() []Ordering {
+	var orders []Ordering
+	for _, col := range sortedKeys(by) {
+		orders = append(orders, Sort(col, by[col]))
+	}
+	return orders
+}
+
 // builder a subset of clauses for the SelectBuilder, InsertBuilder, and DeleteBuilder.
 type baseBuilder struct {
+	CTEs           []*cte
+	JoinClauses    []*joinClause
 	WhereFragments []*whereFragment
 	OrderBys       []string
+	OrderArgs      []interface{} // args of the placeholders in OrderBys
 	LimitCount     uint64
 	LimitValid     bool
 	OffsetCount    uint64
 	OffsetValid    bool
+
+	buildState
+}
+
+func newBaseBuilder(c *Connection) *baseBuilder {
+	return &baseBuilder{buildState: buildState{safe: c.SafeMode}}
+}
+
+// buildState records the first error that occurred while building a query.
+type buildState struct {
+	safe bool // whether the connection is in the safe mode
+	err  error
+}
+
+// checkSafe records an error if the builder is in the safe mode and the SQL
+// fragment contains a literal.
+func (s *buildState) checkSafe(sql string) {
+	if s.safe && s.err == nil {
+		s.err = checkLiterals(sql)
+	}
+}
+
+// fail records err unless an error has already been recorded.
+func (s *buildState) fail(err error) {
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// expr returns e as an Expr. It is checked in the safe mode, and an error
+// of a CASE expression is recorded.
+func (s *buildState) expr(e expression) *expr {
+	x, err := e.toExpr()
+	if err != nil {
+		s.fail(err)
+		return Expr("")
+	}
+	s.checkSafe(x.Sql)
+	return x
+}
+
+// value returns v, or v converted by expr if it is an expression. An error
+// is recorded for an aliased expression.
+func (s *buildState) value(v interface{}) interface{} {
+	switch v := v.(type) {
+	case expression:
+		return s.expr(v)
+	case *aliasedExpr:
+		s.fail(errAliasedExpr)
+	}
+	return v
+}
+
+// values returns a copy of vals converted by value.
+func (s *buildState) values(vals []interface{}) []interface{} {
+	c := make([]interface{}, len(vals))
+	for i, v := range vals {
+		c[i] = s.value(v)
+	}
+	return c
+}
+
+// clone returns a deep copy of b. The subqueries are shared.
+func (b *baseBuilder) clone() *baseBuilder {
+	c := *b
+	c.CTEs = nil
+	for _, t := range b.CTEs {
+		t := *t
+		c.CTEs = append(c.CTEs, &t)
+	}
+	c.JoinClauses = nil
+	for _, j := range b.JoinClauses {
+		j := *j
+		j.OnFragments = cloneFragments(j.OnFragments)
+		c.JoinClauses = append(c.JoinClauses, &j)
+	}
+	c.WhereFragments = cloneFragments(b.WhereFragments)
+	c.OrderBys = cloneStrings(b.OrderBys)
+	c.OrderArgs = cloneValues(b.OrderArgs)
+	return &c
 }
 
 func (b *baseBuilder) where(exprOrMap interface{}, args ...interface{}) {
-	handleExprType(exprOrMap, args, func(expr string, args ...interface{}) {
-		expr, args = handleShortNotation(expr, args)
-		b.WhereFragments = append(b.WhereFragments, &whereFragment{expr, args})
-	})
+	b.WhereFragments = append(b.WhereFragments, b.conditions(exprOrMap, args)...)
+}
+
+func (b *baseBuilder) orderBy(exprOrCase interface{}) {
+	switch e := exprOrCase.(type) {
+	case string:
+		b.OrderBys = append(b.OrderBys, e)
+	case expression:
+		x := b.expr(e)
+		b.OrderBys = append(b.OrderBys, x.Sql)
+		b.OrderArgs = append(b.OrderArgs, x.Values...)
+	default:
+		b.fail(fmt.Errorf("invalid argument of type %T passed to OrderBy, only a string, an Expr, or a CASE expression is allowed", exprOrCase))
+	}
 }
 
-func (b *baseBuilder) orderBy(expr string) {
-	b.OrderBys = append(b.OrderBys, expr)
+func (b *baseBuilder) order(orders []Orderer) {
+	b.OrderBys = append(b.OrderBys, orderExprs(orders)...)
 }
 
-func (b *baseBuilder) order(by By) {
-	for col, dir := range by {
-		expr := "[" + col + "]"
-		if dir == Desc {
-			expr += " DESC"
-		} else {
-			expr += " ASC"
+// orderExprs returns the ORDER BY expressions for the orderings.
+func orderExprs(orders []Orderer) []string {
+	var exprs []string
+	for _, o := range orders {
+		for _, o := range o.orderings() {
+			expr := "[" + o.Column + "]"
+			if o.Desc {
+				expr += " DESC"
+			} else {
+				expr += " ASC"
+			}
+			exprs = append(exprs, expr)
 		}
-		b.orderBy(expr)
 	}
+	return exprs
 }
 
 func (b *baseBuilder) limit(v uint64) {
@@ -59,23 +193,34 @@ func (b *baseBuilder) offset(v uint64) {
 	b.OffsetValid = true
 }
 
-func (b *baseBuilder) buildWhere(w query.Writer, args *[]interface{}) {
+func (b *baseBuilder) buildWhere(w query.Writer, args *[]interface{}) error {
 	if len(b.WhereFragments) > 0 {
 		w.WriteString(" WHERE ")
-		writeWhereFragmentsToSql(w, b.WhereFragments, args)
+		return writeWhereFragmentsToSql(w, b.WhereFragments, args)
 	}
+	return nil
 }
 
-func (b *baseBuilder) buildOrder(w query.Writer) {
-	if len(b.OrderBys) > 0 {
-		w.WriteString(" ORDER BY ")
-		for i, s := range b.OrderBys {
-			if i > 0 {
-				w.WriteString(", ")
-			}
-			w.WriteString(s)
-		}
+func (b *baseBuilder) buildOrder(w query.Writer, args *[]interface{}) error {
+	if len(b.OrderBys) == 0 {
+		return nil
+	}
+	order, orderArgs, err := expandSubqueries(strings.Join(b.OrderBys, ", "), b.OrderArgs)
+	if err != nil {
+		return err
+	}
+	w.WriteString(" ORDER BY ")
+	w.WriteString(order)
+	*args = append(*args, orderArgs...)
+	return nil
+}
+
+// buildReturning writes the RETURNING clause if there are any columns.
+func buildReturning(w query.Writer, stmt query.Statement, cols []string) error {
+	if len(cols) == 0 {
+		return nil
 	}
+	return D.ApplyReturning(w, stmt, cols)
 }
 
 func (b *baseBuilder) buildLimitAndOffset(w query.Writer) {
@@ -0,0 +1,123 @@
+package ql
+
+import (
+	"bytes"
+	"errors"
+)
+
+// CaseBuilder builds a CASE expression, which can be used like an Expr in
+// the columns of a SELECT, in Where, Having, OrderBy, and Set.
+type CaseBuilder struct {
+	whens   []*caseWhen
+	els     interface{}
+	hasElse bool
+	err     error
+}
+
+type caseWhen struct {
+	cond    *expr
+	then    interface{}
+	hasThen bool
+}
+
+// Case returns a new CASE expression. Example of usage:
+//
+//	ql.Case().When("status", "new").Then(1).When("status", "open").Then(2).Else(3)
+func Case() *CaseBuilder {
+	return &CaseBuilder{}
+}
+
+// When appends a WHEN branch with the condition, which accepts the same
+// arguments as Where. It must be followed by Then.
+func (c *CaseBuilder) When(exprOrMap interface{}, args ...interface{}) *CaseBuilder {
+	if n := len(c.whens); n > 0 && !c.whens[n-1].hasThen {
+		c.fail(errors.New("CASE: When must be followed by Then"))
+	}
+	var b baseBuilder
+	sql, vals := b.compileCond(exprOrMap, args)
+	c.fail(b.err)
+	c.whens = append(c.whens, &caseWhen{cond: Expr(sql, vals...)})
+	return c
+}
+
+// Then sets the result of the last WHEN branch. The value is passed as an
+// argument unless it is an Expr or another CASE expression.
+func (c *CaseBuilder) Then(value interface{}) *CaseBuilder {
+	n := len(c.whens)
+	if n == 0 || c.whens[n-1].hasThen {
+		c.fail(errors.New("CASE: Then must follow When"))
+		return c
+	}
+	if _, ok := value.(*aliasedExpr); ok {
+		c.fail(errAliasedExpr)
+	}
+	c.whens[n-1].then = value
+	c.whens[n-1].hasThen = true
+	return c
+}
+
+// Else sets the result if none of the WHEN conditions is true. See Then.
+func (c *CaseBuilder) Else(value interface{}) *CaseBuilder {
+	if _, ok := value.(*aliasedExpr); ok {
+		c.fail(errAliasedExpr)
+	}
+	c.els = value
+	c.hasElse = true
+	return c
+}
+
+// As returns the expression aliased as alias to be used as a column of Select.
+func (c *CaseBuilder) As(alias string) *aliasedExpr {
+	return &aliasedExpr{e: c, alias: alias}
+}
+
+func (c *CaseBuilder) fail(err error) {
+	if c.err == nil {
+		c.err = err
+	}
+}
+
+func (c *CaseBuilder) toExpr() (*expr, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	if len(c.whens) == 0 {
+		return nil, errors.New("CASE: no When specified")
+	}
+	sql := new(bytes.Buffer)
+	var args []interface{}
+	writeValue := func(v interface{}) error {
+		if e, ok := v.(expression); ok {
+			x, err := e.toExpr()
+			if err != nil {
+				return err
+			}
+			sql.WriteString(x.Sql)
+			args = append(args, x.Values...)
+			return nil
+		}
+		sql.WriteString("?")
+		args = append(args, v)
+		return nil
+	}
+
+	sql.WriteString("CASE")
+	for _, w := range c.whens {
+		if !w.hasThen {
+			return nil, errors.New("CASE: When must be followed by Then")
+		}
+		sql.WriteString(" WHEN " + w.cond.Sql + " THEN ")
+		args = append(args, w.cond.Values...)
+		if err := writeValue(w.then); err != nil {
+			return nil, err
+		}
+	}
+	if c.hasElse {
+		sql.WriteString(" ELSE ")
+		if err := writeValue(c.els); err != nil {
+			return nil, err
+		}
+	}
+	sql.WriteString(" END")
+	return Expr(sql.String(), args...), nil
+}
@@ -0,0 +1,73 @@
+package ql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCaseToSql(t *testing.T) {
+	s := createFakeConnection()
+
+	priority := Case().When("status", "new").Then(1).When(Or{"status": "open", "urgent": true}).Then(2).Else(3)
+	sql, args := s.Select("id", Case().When("score >", 90).Then("A").Else(Expr("[grade]"))).
+		From("t").Where("kind = ?", "x").OrderBy(priority).Limit(5).ToSql()
+	assert.Equal(t, sql, "SELECT id, CASE WHEN [score] > ? THEN ? ELSE [grade] END FROM t WHERE ([kind] = ?)"+
+		" ORDER BY CASE WHEN [status] = ? THEN ? WHEN ([status] = ?) OR ([urgent] = ?) THEN ? ELSE ? END LIMIT 5")
+	assert.Equal(t, args, []interface{}{90, "A", "x", "new", 1, "open", true, 2, 3})
+
+	sql, args = s.Update("t").Set("rank", Case().When("score >= ?", 50).Then(Expr("[rank] + ?", 1)).Else(Expr("[rank]"))).
+		Where(Case().When("a", nil).Then(false).Else(true)).ToSql()
+	assert.Equal(t, sql, "UPDATE t SET `rank` = CASE WHEN [score] >= ? THEN [rank] + ? ELSE [rank] END"+
+		" WHERE (CASE WHEN [a] IS NULL THEN ? ELSE ? END)")
+	assert.Equal(t, args, []interface{}{50, 1, false, true})
+}
+
+func TestCaseSubqueryToSql(t *testing.T) {
+	s := createFakeConnection()
+
+	sub := s.Select("user_id").From("ban").Where("until > ?", 7)
+	sql, args := s.Select("id").From("user").OrderBy(Case().When("id", sub).Then(1).Else(0)).ToSql()
+	assert.Equal(t, sql, "SELECT id FROM user ORDER BY CASE WHEN [id] IN (SELECT user_id FROM ban WHERE ([until] > ?)) THEN ? ELSE ? END")
+	assert.Equal(t, args, []interface{}{7, 1, 0})
+
+	sql, args = s.Update("user").Set("banned", Case().When("id", sub).Then(true).Else(false)).
+		Set("score", Expr("? + 1", s.Select("MAX(score)").From("user"))).Where("team", 3).ToSql()
+	assert.Equal(t, sql, "UPDATE user SET `banned` = CASE WHEN [id] IN (SELECT user_id FROM ban WHERE ([until] > ?)) THEN ? ELSE ? END,"+
+		" `score` = (SELECT MAX(score) FROM user) + 1 WHERE ([team] = ?)")
+	assert.Equal(t, args, []interface{}{7, true, false, 3})
+
+	sql, args = s.InsertInto("user").Pair("id", 1).DoUpdateSet("score", Expr("?", s.Select("MAX(score)").From("user"))).ToSql()
+	assert.Equal(t, sql, "INSERT INTO user (`id`) VALUES (?) ON DUPLICATE KEY UPDATE `score` = (SELECT MAX(score) FROM user)")
+	assert.Equal(t, args, []interface{}{1})
+
+	_, _, err := s.Select("id").From("user").OrderBy(Case().When("id", s.Select("a").Where("b = ?", 1)).Then(1)).ToSqlErr()
+	assert.Equal(t, err, ErrNoTable)
+}
+
+func TestCaseErrors(t *testing.T) {
+	s := createFakeConnection()
+
+	tests := []struct {
+		c   *CaseBuilder
+		err string
+	}{
+		{Case(), "CASE: no When specified"},
+		{Case().Then(1), "CASE: Then must follow When"},
+		{Case().When("a", 1), "CASE: When must be followed by Then"},
+		{Case().When("a", 1).When("b", 2).Then(3), "CASE: When must be followed by Then"},
+		{Case().When(AndList{"a"}).Then(1), "odd number of elements in a list of conditions"},
+	}
+	for _, test := range tests {
+		_, _, err := s.Select(test.c).From("t").ToSqlErr()
+		if assert.NotNil(t, err) {
+			assert.Equal(t, err.Error(), test.err)
+		}
+		_, _, err = s.Update("t").Set("a", test.c).ToSqlErr()
+		assert.NotNil(t, err)
+	}
+
+	s.SafeMode = true
+	_, _, err := s.Select("a").From("t").OrderBy(Case().When("a = 'x'").Then(1)).ToSqlErr()
+	assert.IsType(t, err, &LiteralError{})
+}
@@ -0,0 +1,159 @@
+package ql
+
+import "bytes"
+
+// CompoundBuilder contains the clauses for a compound SELECT statement, which
+// combines the results of several SELECT statements using UNION, INTERSECT,
+// or EXCEPT.
+type CompoundBuilder struct {
+	// methods for loading structs and values
+	loader
+
+	Parts []*compoundPart
+	*baseBuilder
+}
+
+type compoundPart struct {
+	Op     string // empty for the first part
+	Select *SelectBuilder
+}
+
+func newCompoundBuilder(c *Connection, r runner, first *SelectBuilder) *CompoundBuilder {
+	b := &CompoundBuilder{
+		loader:      loader{EventReceiver: c, runner: r},
+		baseBuilder: newBaseBuilder(c),
+	}
+	b.loader.builder = b
+	return b.add("", first)
+}
+
+// Compound creates a new CompoundBuilder starting with the first SELECT
+// statement. Other statements are added using the Union, UnionAll, Intersect,
+// and Except methods.
+func (db *Connection) Compound(first *SelectBuilder) *CompoundBuilder {
+	return newCompoundBuilder(db, db.DB, first)
+}
+
+// Compound creates a new CompoundBuilder starting with the first SELECT
+// statement bound to the transaction.
+func (tx *Tx) Compound(first *SelectBuilder) *CompoundBuilder {
+	return newCompoundBuilder(tx.Connection, tx.Tx, first)
+}
+
+func (b *CompoundBuilder) add(op string, sels ...*SelectBuilder) *CompoundBuilder {
+	for _, s := range sels {
+		b.Parts = append(b.Parts, &compoundPart{Op: op, Select: s})
+	}
+	return b
+}
+
+// Union combines the statement with the given SELECT statements using UNION.
+func (b *CompoundBuilder) Union(sels ...*SelectBuilder) *CompoundBuilder {
+	return b.add("UNION", sels...)
+}
+
+// UnionAll combines the statement with the given SELECT statements using
+// UNION ALL.
+func (b *CompoundBuilder) UnionAll(sels ...*SelectBuilder) *CompoundBuilder {
+	return b.add("UNION ALL", sels...)
+}
+
+// Intersect combines the statement with the given SELECT statements using
+// INTERSECT.
+func (b *CompoundBuilder) Intersect(sels ...*SelectBuilder) *CompoundBuilder {
+	return b.add("INTERSECT", sels...)
+}
+
+// Except combines the statement with the given SELECT statements using
+// EXCEPT.
+func (b *CompoundBuilder) Except(sels ...*SelectBuilder) *CompoundBuilder {
+	return b.add("EXCEPT", sels...)
+}
+
+// OrderBy appends a column (or an Expr or a CASE expression) to ORDER the
+// whole statement by.
+func (b *CompoundBuilder) OrderBy(expr interface{}) *CompoundBuilder {
+	b.orderBy(expr)
+	return b
+}
+
+// Order appends the orderings (Asc, Desc, or a By map) to ORDER the whole statement by.
+func (b *CompoundBuilder) Order(orders ...Orderer) *CompoundBuilder {
+	b.order(orders)
+	return b
+}
+
+// Limit sets a limit for the whole statement; overrides any existing LIMIT.
+func (b *CompoundBuilder) Limit(limit uint64) *CompoundBuilder {
+	b.limit(limit)
+	return b
+}
+
+// Offset sets an offset for the whole statement; overrides any existing OFFSET.
+func (b *CompoundBuilder) Offset(offset uint64) *CompoundBuilder {
+	b.offset(offset)
+	return b
+}
+
+// Clone returns a deep copy of the builder, which can be modified without
+// affecting b. Subqueries are shared.
+func (b *CompoundBuilder) Clone() *CompoundBuilder {
+	c := *b
+	c.Parts = nil
+	for _, p := range b.Parts {
+		p := *p
+		c.Parts = append(c.Parts, &p)
+	}
+	c.baseBuilder = b.baseBuilder.clone()
+	c.loader.builder = &c
+	return &c
+}
+
+// ToSql serialized the CompoundBuilder to a SQL string. It returns the string with
+// placeholders and a slice of query arguments. It returns an empty string if
+// the statement cannot be built; see ToSqlErr.
+func (b *CompoundBuilder) ToSql() (string, []interface{}) {
+	sql, args, _ := b.ToSqlErr()
+	return sql, args
+}
+
+// ToSqlErr is like ToSql, but it also returns the first error that occurred
+// while building the statement.
+func (b *CompoundBuilder) ToSqlErr() (string, []interface{}, error) {
+	if b.err != nil {
+		return "", nil, b.err
+	}
+	if len(b.Parts) == 0 {
+		return "", nil, ErrNoSelects
+	}
+
+	sql := new(bytes.Buffer)
+	var args []interface{}
+
+	for _, p := range b.Parts {
+		if p.Op != "" {
+			sql.WriteString(" " + p.Op + " ")
+		}
+		partSql, partArgs, err := p.Select.ToSqlErr()
+		if err != nil {
+			return "", nil, err
+		}
+		sql.WriteString("(" + partSql + ")")
+		args = append(args, partArgs...)
+	}
+
+	if err := b.buildOrder(sql, &args); err != nil {
+		return "", nil, err
+	}
+	b.buildLimitAndOffset(sql)
+
+	return sql.String(), args, nil
+}
+
+// One executes the query and loads the resulting data into the dest, which can be either
+// a struct, or a primitive value. Returns ErrNotFound if no item was found, and it was
+// therefore not set. The query is executed with LIMIT 1, which is not kept
+// in the builder.
+func (b *CompoundBuilder) One(dest interface{}) error {
+	return b.Clone().Limit(1).loader.One(dest)
+}
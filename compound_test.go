@@ -0,0 +1,33 @@
+package ql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompoundToSql(t *testing.T) {
+	s := createFakeConnection()
+
+	sql, args := s.Compound(s.Select("id", "created").From("post").Where("author", 1)).
+		UnionAll(s.Select("id", "created").From("comment").Where("author", 2)).
+		Union(s.Select("id", "created").From("vote").Where("author", 3)).
+		Intersect(s.Select("id", "created").From("visible")).
+		Except(s.Select("id", "created").From("hidden").Where("author", 4)).
+		OrderBy("created DESC").
+		Limit(20).
+		ToSql()
+
+	assert.Equal(t, sql, "(SELECT id, created FROM post WHERE ([author] = ?))"+
+		" UNION ALL (SELECT id, created FROM comment WHERE ([author] = ?))"+
+		" UNION (SELECT id, created FROM vote WHERE ([author] = ?))"+
+		" INTERSECT (SELECT id, created FROM visible)"+
+		" EXCEPT (SELECT id, created FROM hidden WHERE ([author] = ?))"+
+		" ORDER BY created DESC LIMIT 20")
+	assert.Equal(t, args, []interface{}{1, 2, 3, 4})
+
+	union := s.Compound(s.Select("user_id").From("post")).Union(s.Select("user_id").From("comment"))
+	sql, args = s.Select("name").From("user").Where("id", union).ToSql()
+	assert.Equal(t, sql, "SELECT name FROM user WHERE ([id] IN ((SELECT user_id FROM post) UNION (SELECT user_id FROM comment)))")
+	assert.Equal(t, len(args), 0)
+}
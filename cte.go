@@ -0,0 +1,40 @@
+package ql
+
+import "github.com/mibk/ql/query"
+
+// cte is a common table expression of a WITH clause.
+type cte struct {
+	Name      string
+	Query     queryBuilder
+	Recursive bool
+}
+
+func (b *baseBuilder) with(name string, q queryBuilder, recursive bool) {
+	b.CTEs = append(b.CTEs, &cte{Name: name, Query: q, Recursive: recursive})
+}
+
+func (b *baseBuilder) buildWith(w query.Writer, args *[]interface{}) error {
+	if len(b.CTEs) == 0 {
+		return nil
+	}
+	w.WriteString("WITH ")
+	for _, c := range b.CTEs {
+		if c.Recursive {
+			w.WriteString("RECURSIVE ")
+			break
+		}
+	}
+	for i, c := range b.CTEs {
+		if i > 0 {
+			w.WriteString(", ")
+		}
+		sql, cteArgs, err := c.Query.ToSqlErr()
+		if err != nil {
+			return err
+		}
+		w.WriteString(c.Name + " AS (" + sql + ")")
+		*args = append(*args, cteArgs...)
+	}
+	w.WriteString(" ")
+	return nil
+}
@@ -8,11 +8,11 @@ import (
 type date time.Time
 
 // Date turns time into date type. It is supposed to be used in queries to use
-// 2006-01-02 time format.
+// 2006-01-02 time format. The date is taken in the time zone of the dialect D.
 func Date(t time.Time) date {
 	return date(t)
 }
 
 func (d date) Value() (driver.Value, error) {
-	return time.Time(d).Format("2006-01-02"), nil
+	return time.Time(d).In(D.TimeLocation()).Format("2006-01-02"), nil
 }
@@ -1,22 +1,29 @@
 package ql
 
-import "bytes"
+import (
+	"bytes"
+	"strings"
+
+	"github.com/mibk/ql/query"
+)
 
 // DeleteBuilder contains the clauses for a DELETE statement.
 type DeleteBuilder struct {
-	executor
+	modifier // Exec and methods for loading the returned rows
 
-	From string
+	From             string
+	DeleteTables     []string
+	ReturningColumns []string
 	*baseBuilder
 }
 
-func newDeleteBuilder(e EventReceiver, r runner, from string) *DeleteBuilder {
+func newDeleteBuilder(c *Connection, r runner, from string) *DeleteBuilder {
 	b := &DeleteBuilder{
-		executor:    executor{EventReceiver: e, runner: r},
+		modifier:    newModifier(c, r, "ql.delete"),
 		From:        from,
-		baseBuilder: new(baseBuilder),
+		baseBuilder: newBaseBuilder(c),
 	}
-	b.executor.builder = b
+	b.loader.builder = b
 	return b
 }
 
@@ -31,6 +38,54 @@ func (tx *Tx) DeleteFrom(from string) *DeleteBuilder {
 	return newDeleteBuilder(tx.Connection, tx.Tx, from)
 }
 
+// With adds a common table expression named name (which may include a list
+// of columns, e.g. "t(a, b)") to the WITH clause of the statement.
+func (b *DeleteBuilder) With(name string, sub *SelectBuilder) *DeleteBuilder {
+	b.with(name, sub, false)
+	return b
+}
+
+// WithRecursive adds a recursive common table expression to the WITH clause
+// of the statement. The sub is usually a UNION ALL of the initial SELECT and
+// the SELECT referencing name. See With.
+func (b *DeleteBuilder) WithRecursive(name string, sub *CompoundBuilder) *DeleteBuilder {
+	b.with(name, sub, true)
+	return b
+}
+
+// Tables sets the tables to delete the rows from if the statement contains
+// joins. If not set, the rows are deleted from the table From (or its alias).
+func (b *DeleteBuilder) Tables(tables ...string) *DeleteBuilder {
+	b.DeleteTables = tables
+	return b
+}
+
+// Join appends a JOIN of the table (aliased as alias if not empty) to the
+// statement. The ON condition is a string with args or an And map, as in Where.
+func (b *DeleteBuilder) Join(table, alias string, onExprOrMap interface{}, args ...interface{}) *DeleteBuilder {
+	b.join("JOIN", table, alias, onExprOrMap, args)
+	return b
+}
+
+// LeftJoin appends a LEFT JOIN to the statement. See Join.
+func (b *DeleteBuilder) LeftJoin(table, alias string, onExprOrMap interface{}, args ...interface{}) *DeleteBuilder {
+	b.join("LEFT JOIN", table, alias, onExprOrMap, args)
+	return b
+}
+
+// RightJoin appends a RIGHT JOIN to the statement. See Join.
+func (b *DeleteBuilder) RightJoin(table, alias string, onExprOrMap interface{}, args ...interface{}) *DeleteBuilder {
+	b.join("RIGHT JOIN", table, alias, onExprOrMap, args)
+	return b
+}
+
+// CrossJoin appends a CROSS JOIN of the table (aliased as alias if not empty)
+// to the statement.
+func (b *DeleteBuilder) CrossJoin(table, alias string) *DeleteBuilder {
+	b.join("CROSS JOIN", table, alias, nil, nil)
+	return b
+}
+
 // Where appends a WHERE clause to the statement whereSqlOrMap can be a string or map.
 // If it's a string, args wil replaces any places holders.
 func (b *DeleteBuilder) Where(whereSqlOrMap interface{}, args ...interface{}) *DeleteBuilder {
@@ -38,15 +93,16 @@ func (b *DeleteBuilder) Where(whereSqlOrMap interface{}, args ...interface{}) *D
 	return b
 }
 
-// OrderBy appends an ORDER BY clause to the statement.
-func (b *DeleteBuilder) OrderBy(expr string) *DeleteBuilder {
+// OrderBy appends an ORDER BY clause (a column, or an Expr or a CASE
+// expression) to the statement.
+func (b *DeleteBuilder) OrderBy(expr interface{}) *DeleteBuilder {
 	b.orderBy(expr)
 	return b
 }
 
-// Order accepts By map of columns and directions to ORDER the statement by.
-func (b *DeleteBuilder) Order(by By) *DeleteBuilder {
-	b.order(by)
+// Order appends the orderings (Asc, Desc, or a By map) to ORDER the statement by.
+func (b *DeleteBuilder) Order(orders ...Orderer) *DeleteBuilder {
+	b.order(orders)
 	return b
 }
 
@@ -62,22 +118,79 @@ func (b *DeleteBuilder) Offset(offset uint64) *DeleteBuilder {
 	return b
 }
 
-// ToSql serialized the DeleteBuilder to a SQL string.  It returns the string with
-// placeholders and a slice of query arguments.
+// Returning sets the columns (or expressions) of the affected rows returned
+// by the statement, which can be loaded using All or One. It is supported by
+// dialect.Postgres and dialect.MariaDB.
+func (b *DeleteBuilder) Returning(columns ...string) *DeleteBuilder {
+	b.ReturningColumns = columns
+	return b
+}
+
+// Clone returns a deep copy of the builder, which can be modified without
+// affecting b. Subqueries are shared.
+func (b *DeleteBuilder) Clone() *DeleteBuilder {
+	c := *b
+	c.DeleteTables = cloneStrings(b.DeleteTables)
+	c.ReturningColumns = cloneStrings(b.ReturningColumns)
+	c.baseBuilder = b.baseBuilder.clone()
+	c.loader.builder = &c
+	return &c
+}
+
+// ToSql serialized the DeleteBuilder to a SQL string. It returns the string with
+// placeholders and a slice of query arguments. It returns an empty string if
+// the statement cannot be built; see ToSqlErr.
 func (b *DeleteBuilder) ToSql() (string, []interface{}) {
+	sql, args, _ := b.ToSqlErr()
+	return sql, args
+}
+
+// ToSqlErr is like ToSql, but it also returns the first error that occurred
+// while building the statement.
+func (b *DeleteBuilder) ToSqlErr() (string, []interface{}, error) {
+	if b.err != nil {
+		return "", nil, b.err
+	}
 	if len(b.From) == 0 {
-		panic("no table specified")
+		return "", nil, ErrNoTable
 	}
 
 	sql := new(bytes.Buffer)
 	var args []interface{}
 
-	sql.WriteString("DELETE FROM ")
+	if err := b.buildWith(sql, &args); err != nil {
+		return "", nil, err
+	}
+	if len(b.JoinClauses) > 0 {
+		// multi-table DELETE
+		tables := b.DeleteTables
+		if len(tables) == 0 {
+			// the alias, or the table name if there is none
+			f := strings.Fields(b.From)
+			tables = f[len(f)-1:]
+		}
+		sql.WriteString("DELETE ")
+		sql.WriteString(strings.Join(tables, ", "))
+		sql.WriteString(" FROM ")
+	} else {
+		sql.WriteString("DELETE FROM ")
+	}
 	sql.WriteString(b.From)
+	if err := b.buildJoins(sql, &args); err != nil {
+		return "", nil, err
+	}
 
-	b.buildWhere(sql, &args)
-	b.buildOrder(sql)
+	if err := b.buildWhere(sql, &args); err != nil {
+		return "", nil, err
+	}
+	if err := b.buildOrder(sql, &args); err != nil {
+		return "", nil, err
+	}
 	b.buildLimitAndOffset(sql)
 
-	return sql.String(), args
+	if err := buildReturning(sql, query.DeleteStmt, b.ReturningColumns); err != nil {
+		return "", nil, err
+	}
+
+	return sql.String(), args, nil
 }
@@ -3,6 +3,7 @@ package ql
 import (
 	"testing"
 
+	"github.com/mibk/ql/dialect"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -66,3 +67,34 @@ func TestDeleteReal(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, count, int64(0))
 }
+
+func TestDeleteJoinToSql(t *testing.T) {
+	s := createFakeConnection()
+
+	sql, args := s.DeleteFrom("user u").LeftJoin("post", "p", "p.user_id = u.id").Where("p.id", nil).ToSql()
+	assert.Equal(t, sql, "DELETE u FROM user u LEFT JOIN post `p` ON (p.user_id = u.id) WHERE ([p.id] IS NULL)")
+	assert.Equal(t, len(args), 0)
+
+	sql, args = s.DeleteFrom("user").Tables("user", "p").Join("post", "p", And{"p.user_id": 3}).ToSql()
+	assert.Equal(t, sql, "DELETE user, p FROM user JOIN post `p` ON ([p.user_id] = ?)")
+	assert.Equal(t, args, []interface{}{3})
+}
+
+func TestDeleteWithToSql(t *testing.T) {
+	s := createFakeConnection()
+
+	sql, args := s.DeleteFrom("session").With("old", s.Select("id").From("user").Where("seen <", 2010)).
+		Where("id IN (SELECT id FROM old)").ToSql()
+	assert.Equal(t, sql, "WITH old AS (SELECT id FROM user WHERE ([seen] < ?)) DELETE FROM session WHERE (id IN (SELECT id FROM old))")
+	assert.Equal(t, args, []interface{}{2010})
+}
+
+func TestDeleteReturningToSql(t *testing.T) {
+	s := createFakeConnection()
+	defer func(d Dialect) { D = d }(D)
+	D = dialect.MariaDB{}
+
+	sql, args := s.DeleteFrom("a").Where("id", 5).Returning("*").ToSql()
+	assert.Equal(t, sql, "DELETE FROM a WHERE ([id] = ?) RETURNING *")
+	assert.Equal(t, args, []interface{}{5})
+}
@@ -0,0 +1,47 @@
+// Package dialect contains the SQL dialects of the supported databases.
+package dialect
+
+import (
+	"strings"
+	"time"
+
+	"github.com/mibk/ql/query"
+)
+
+// UnsupportedError is returned when a dialect has no equivalent of a feature.
+type UnsupportedError struct {
+	Dialect string
+	Feature string
+}
+
+func (e *UnsupportedError) Error() string {
+	return "dialect: " + e.Feature + " is not supported by " + e.Dialect
+}
+
+// writeReturning writes the RETURNING clause with the columns.
+func writeReturning(w query.Writer, cols []string) {
+	w.WriteString(" RETURNING ")
+	w.WriteString(strings.Join(cols, ", "))
+}
+
+const timeFormat = "2006-01-02 15:04:05"
+
+func timeLocation(loc *time.Location) *time.Location {
+	if loc == nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// formatTime formats t in the location (UTC if nil) with the given number
+// of fractional second digits (0 to 6).
+func formatTime(t time.Time, loc *time.Location, precision int) string {
+	format := timeFormat
+	if precision > 0 {
+		if precision > 6 {
+			precision = 6
+		}
+		format += "." + strings.Repeat("0", precision)
+	}
+	return t.In(timeLocation(loc)).Format(format)
+}
@@ -0,0 +1,20 @@
+package dialect
+
+import "github.com/mibk/ql/query"
+
+// MariaDB is the dialect of MariaDB. It is the same as Mysql except that
+// it supports the RETURNING clause (of INSERT and REPLACE since 10.5, of
+// DELETE since 10.0).
+type MariaDB struct {
+	Mysql
+}
+
+// ApplyReturning returns an error for UPDATE, which has no RETURNING clause
+// in MariaDB.
+func (MariaDB) ApplyReturning(w query.Writer, stmt query.Statement, cols []string) error {
+	if stmt == query.UpdateStmt {
+		return &UnsupportedError{Dialect: "MariaDB", Feature: "UPDATE ... RETURNING"}
+	}
+	writeReturning(w, cols)
+	return nil
+}
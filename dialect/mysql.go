@@ -8,9 +8,21 @@ import (
 	"github.com/mibk/ql/query"
 )
 
-const mysqlTimeFormat = "2006-01-02 15:04:05"
+// Mysql is the dialect of MySQL. The zero value writes times in UTC
+// without fractional seconds.
+type Mysql struct {
+	// Location is the time zone times are converted to before they are
+	// written to a query. If nil, UTC is used.
+	Location *time.Location
 
-type Mysql struct{}
+	// Precision is the number of fractional second digits (0 to 6) written
+	// for times, e.g. 6 for DATETIME(6) columns.
+	Precision int
+}
+
+func (Mysql) IdentQuote() rune { return '`' }
+
+func (Mysql) BackslashEscapes() bool { return true }
 
 func (Mysql) EscapeIdent(w query.Writer, ident string) {
 	w.WriteRune('`')
@@ -55,7 +67,12 @@ func (Mysql) EscapeString(w query.Writer, s string) {
 }
 
 func (d Mysql) EscapeTime(w query.Writer, t time.Time) {
-	d.EscapeString(w, t.Format(mysqlTimeFormat))
+	d.EscapeString(w, formatTime(t, d.Location, d.Precision))
+}
+
+// TimeLocation returns the time zone times are converted to.
+func (d Mysql) TimeLocation() *time.Location {
+	return timeLocation(d.Location)
 }
 
 func (Mysql) ApplyLimitAndOffset(w query.Writer, limit, offset uint64) {
@@ -70,3 +87,74 @@ func (Mysql) ApplyLimitAndOffset(w query.Writer, limit, offset uint64) {
 		fmt.Fprintf(w, " OFFSET %d", offset)
 	}
 }
+
+// ApplyLock returns an error if OF, NOWAIT, or SKIP LOCKED is used with
+// LOCK IN SHARE MODE, which supports none of them.
+func (d Mysql) ApplyLock(w query.Writer, lock query.Lock) error {
+	switch lock.Mode {
+	case query.ForUpdate:
+		w.WriteString(" FOR UPDATE")
+	case query.ForShare:
+		w.WriteString(" FOR SHARE")
+	case query.LockInShareMode:
+		if len(lock.Tables) > 0 || lock.Wait != query.Wait {
+			return &UnsupportedError{Dialect: "MySQL", Feature: "OF, NOWAIT, or SKIP LOCKED with LOCK IN SHARE MODE"}
+		}
+		w.WriteString(" LOCK IN SHARE MODE")
+		return nil
+	default:
+		return nil
+	}
+	for i, t := range lock.Tables {
+		if i == 0 {
+			w.WriteString(" OF ")
+		} else {
+			w.WriteString(", ")
+		}
+		d.EscapeIdent(w, t)
+	}
+	switch lock.Wait {
+	case query.NoWait:
+		w.WriteString(" NOWAIT")
+	case query.SkipLocked:
+		w.WriteString(" SKIP LOCKED")
+	}
+	return nil
+}
+
+func (Mysql) ApplyInsert(w query.Writer, mode query.InsertMode) error {
+	switch mode {
+	case query.InsertIgnore:
+		w.WriteString("INSERT IGNORE INTO ")
+	case query.Replace:
+		w.WriteString("REPLACE INTO ")
+	default:
+		w.WriteString("INSERT INTO ")
+	}
+	return nil
+}
+
+func (d Mysql) ApplyUpsert(w query.Writer, u query.Upsert) error {
+	if u.Ignore && u.DoNothing {
+		return nil // INSERT IGNORE already skips the conflicting rows
+	}
+	w.WriteString(" ON DUPLICATE KEY UPDATE ")
+	if u.DoNothing && len(u.Columns) > 0 {
+		// There is no DO NOTHING; assigning a column to itself is a no-op.
+		d.EscapeIdent(w, u.Columns[0])
+		w.WriteString(" = ")
+		d.EscapeIdent(w, u.Columns[0])
+	}
+	return nil
+}
+
+// ApplyReturning returns an error as MySQL has no RETURNING clause; see MariaDB.
+func (Mysql) ApplyReturning(w query.Writer, stmt query.Statement, cols []string) error {
+	return &UnsupportedError{Dialect: "MySQL", Feature: "RETURNING"}
+}
+
+func (d Mysql) EscapeInserted(w query.Writer, col string) {
+	w.WriteString("VALUES(")
+	d.EscapeIdent(w, col)
+	w.WriteRune(')')
+}
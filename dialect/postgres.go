@@ -0,0 +1,139 @@
+package dialect
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mibk/ql/query"
+)
+
+// Postgres is the dialect of PostgreSQL. The zero value writes times in UTC
+// without fractional seconds.
+type Postgres struct {
+	// Location is the time zone times are converted to before they are
+	// written to a query. If nil, UTC is used.
+	Location *time.Location
+
+	// Precision is the number of fractional second digits (0 to 6) written
+	// for times.
+	Precision int
+}
+
+func (Postgres) IdentQuote() rune { return '"' }
+
+// BackslashEscapes returns false; see EscapeString.
+func (Postgres) BackslashEscapes() bool { return false }
+
+func (Postgres) EscapeIdent(w query.Writer, ident string) {
+	w.WriteRune('"')
+	r := strings.NewReplacer(`"`, `""`, ".", `"."`)
+	w.WriteString(r.Replace(ident))
+	w.WriteRune('"')
+}
+
+func (Postgres) EscapeBool(w query.Writer, b bool) {
+	if b {
+		w.WriteString("TRUE")
+	} else {
+		w.WriteString("FALSE")
+	}
+}
+
+// EscapeString returns a quoted string with ' doubled; it expects
+// standard_conforming_strings to be on, so backslashes are not escaped.
+func (Postgres) EscapeString(w query.Writer, s string) {
+	w.WriteRune('\'')
+	w.WriteString(strings.Replace(s, "'", "''", -1))
+	w.WriteRune('\'')
+}
+
+func (d Postgres) EscapeTime(w query.Writer, t time.Time) {
+	d.EscapeString(w, formatTime(t, d.Location, d.Precision))
+}
+
+// TimeLocation returns the time zone times are converted to.
+func (d Postgres) TimeLocation() *time.Location {
+	return timeLocation(d.Location)
+}
+
+func (Postgres) ApplyLimitAndOffset(w query.Writer, limit, offset uint64) {
+	if limit > 0 {
+		fmt.Fprintf(w, " LIMIT %d", limit)
+	}
+	if offset > 0 {
+		fmt.Fprintf(w, " OFFSET %d", offset)
+	}
+}
+
+func (d Postgres) ApplyLock(w query.Writer, lock query.Lock) error {
+	switch lock.Mode {
+	case query.ForUpdate:
+		w.WriteString(" FOR UPDATE")
+	case query.ForShare, query.LockInShareMode:
+		w.WriteString(" FOR SHARE")
+	default:
+		return nil
+	}
+	for i, t := range lock.Tables {
+		if i == 0 {
+			w.WriteString(" OF ")
+		} else {
+			w.WriteString(", ")
+		}
+		d.EscapeIdent(w, t)
+	}
+	switch lock.Wait {
+	case query.NoWait:
+		w.WriteString(" NOWAIT")
+	case query.SkipLocked:
+		w.WriteString(" SKIP LOCKED")
+	}
+	return nil
+}
+
+// ApplyInsert writes INSERT INTO for the InsertIgnore mode as well; the rows
+// are skipped by ON CONFLICT DO NOTHING written by ApplyUpsert. The Replace
+// mode is not supported.
+func (Postgres) ApplyInsert(w query.Writer, mode query.InsertMode) error {
+	if mode == query.Replace {
+		return &UnsupportedError{Dialect: "PostgreSQL", Feature: "REPLACE INTO"}
+	}
+	w.WriteString("INSERT INTO ")
+	return nil
+}
+
+// ApplyUpsert returns an error if the columns are to be updated, but there
+// is no conflict target, which DO UPDATE requires.
+func (d Postgres) ApplyUpsert(w query.Writer, u query.Upsert) error {
+	if !u.DoNothing && len(u.Target) == 0 {
+		return &UnsupportedError{Dialect: "PostgreSQL", Feature: "ON CONFLICT DO UPDATE without a conflict target"}
+	}
+	w.WriteString(" ON CONFLICT")
+	if len(u.Target) > 0 {
+		w.WriteString(" (")
+		for i, col := range u.Target {
+			if i > 0 {
+				w.WriteString(", ")
+			}
+			d.EscapeIdent(w, col)
+		}
+		w.WriteRune(')')
+	}
+	if u.DoNothing {
+		w.WriteString(" DO NOTHING")
+	} else {
+		w.WriteString(" DO UPDATE SET ")
+	}
+	return nil
+}
+
+func (Postgres) ApplyReturning(w query.Writer, stmt query.Statement, cols []string) error {
+	writeReturning(w, cols)
+	return nil
+}
+
+func (d Postgres) EscapeInserted(w query.Writer, col string) {
+	w.WriteString("EXCLUDED.")
+	d.EscapeIdent(w, col)
+}
@@ -0,0 +1,230 @@
+// Package driver provides database/sql drivers which preprocess every
+// statement by ql.Preprocess before it is passed to the wrapped driver. That
+// is, [ident] escaping, expanding slices for IN ?, and quote normalisation
+// work also for libraries accepting only a *sql.DB. Example of usage:
+//
+//	import (
+//		_ "github.com/go-sql-driver/mysql"
+//		_ "github.com/mibk/ql/driver"
+//	)
+//
+//	db, err := sql.Open("ql:mysql", "root@/database")
+//
+// As ql.Preprocess interpolates the arguments into the statement, the wrapped
+// driver always receives statements without arguments.
+package driver
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+
+	"github.com/mibk/ql"
+)
+
+// Prefix is prepended to the names of the wrapped drivers.
+const Prefix = "ql:"
+
+func init() {
+	Register("mysql")
+}
+
+// Register registers a driver named Prefix+name, which wraps the driver
+// registered as name. The wrapped driver is looked up when the first
+// connection is opened, so it does not have to be registered yet. It panics
+// if Register is called twice for the same name.
+func Register(name string) {
+	sql.Register(Prefix+name, &Driver{Name: name})
+}
+
+// Driver is a database/sql driver wrapping the driver registered as Name.
+type Driver struct {
+	Name string
+
+	once sync.Once
+	drv  driver.Driver
+	err  error
+}
+
+// Open opens a new connection using the wrapped driver.
+func (d *Driver) Open(dsn string) (driver.Conn, error) {
+	d.once.Do(func() {
+		// sql.Open only validates the driver name; no connection is made.
+		db, err := sql.Open(d.Name, "")
+		if err != nil {
+			d.err = err
+			return
+		}
+		d.drv = db.Driver()
+		d.err = db.Close()
+	})
+	if d.err != nil {
+		return nil, d.err
+	}
+	c, err := d.drv.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{c}, nil
+}
+
+var errNamedArgs = errors.New("ql/driver: named arguments are not supported")
+
+type conn struct {
+	driver.Conn
+}
+
+// preprocess interpolates the args into the query.
+func preprocess(query string, args []driver.NamedValue) (string, error) {
+	vals := make([]interface{}, len(args))
+	for i, a := range args {
+		if a.Name != "" {
+			return "", errNamedArgs
+		}
+		vals[i] = a.Value
+	}
+	return ql.Preprocess(query, vals)
+}
+
+// CheckNamedValue accepts all values as they are interpolated by
+// ql.Preprocess, which also supports e.g. slices.
+func (c *conn) CheckNamedValue(*driver.NamedValue) error {
+	return nil
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return &stmt{c, query}, nil
+}
+
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	return &stmt{c, query}, nil
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *conn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	query, err := preprocess(query, args)
+	if err != nil {
+		return nil, err
+	}
+	if e, ok := c.Conn.(driver.ExecerContext); ok {
+		res, err := e.ExecContext(ctx, query, nil)
+		if err != driver.ErrSkip {
+			return res, err
+		}
+	}
+	s, err := c.prepare(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+	if e, ok := s.(driver.StmtExecContext); ok {
+		return e.ExecContext(ctx, nil)
+	}
+	return s.Exec(nil)
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	query, err := preprocess(query, args)
+	if err != nil {
+		return nil, err
+	}
+	if q, ok := c.Conn.(driver.QueryerContext); ok {
+		rows, err := q.QueryContext(ctx, query, nil)
+		if err != driver.ErrSkip {
+			return rows, err
+		}
+	}
+	s, err := c.prepare(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	var rows driver.Rows
+	if q, ok := s.(driver.StmtQueryContext); ok {
+		rows, err = q.QueryContext(ctx, nil)
+	} else {
+		rows, err = s.Query(nil)
+	}
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	return &stmtRows{rows, s}, nil
+}
+
+// prepare prepares the already preprocessed query using the wrapped driver.
+func (c *conn) prepare(ctx context.Context, query string) (driver.Stmt, error) {
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return p.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+// stmt is a statement which is preprocessed only when it is executed
+// because the arguments are needed for that.
+type stmt struct {
+	c     *conn
+	query string
+}
+
+func (s *stmt) Close() error  { return nil }
+func (s *stmt) NumInput() int { return -1 }
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.c.ExecContext(context.Background(), s.query, named(args))
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.c.QueryContext(context.Background(), s.query, named(args))
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.c.ExecContext(ctx, s.query, args)
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.c.QueryContext(ctx, s.query, args)
+}
+
+func named(args []driver.Value) []driver.NamedValue {
+	nv := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		nv[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return nv
+}
+
+// stmtRows closes the statement the rows come from together with the rows.
+type stmtRows struct {
+	driver.Rows
+	s driver.Stmt
+}
+
+func (r *stmtRows) Close() error {
+	err := r.Rows.Close()
+	if err2 := r.s.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
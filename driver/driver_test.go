@@ -0,0 +1,105 @@
+package driver
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"testing"
+)
+
+// fakeDriver records the executed queries.
+type fakeDriver struct {
+	queries []string
+}
+
+func (d *fakeDriver) Open(dsn string) (driver.Conn, error) {
+	return &fakeConn{d}, nil
+}
+
+type fakeConn struct {
+	d *fakeDriver
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{c.d, query}, nil
+}
+
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return c, nil }
+func (c *fakeConn) Commit() error             { return nil }
+func (c *fakeConn) Rollback() error           { return nil }
+
+type fakeStmt struct {
+	d     *fakeDriver
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return 0 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.queries = append(s.d.queries, s.query)
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.queries = append(s.d.queries, s.query)
+	return fakeRows{}, nil
+}
+
+type fakeRows struct{}
+
+func (fakeRows) Columns() []string              { return []string{"a"} }
+func (fakeRows) Close() error                   { return nil }
+func (fakeRows) Next(dest []driver.Value) error { return io.EOF }
+
+func TestDriver(t *testing.T) {
+	fake := new(fakeDriver)
+	sql.Register("fake", fake)
+	Register("fake")
+
+	db, err := sql.Open("ql:fake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(`UPDATE [user] SET [name] = "x" WHERE [id] IN ?`, []int{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := db.Query("SELECT [a] FROM [b] WHERE [c] = ?", "it's")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Exec("DELETE FROM [b] WHERE [c] = ?", 3); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Exec("DELETE FROM [b] -- don't use ?\nWHERE [c] = ?", 4); err != nil {
+		t.Fatal(err)
+	}
+	tx.Commit()
+
+	want := []string{
+		"UPDATE `user` SET `name` = 'x' WHERE `id` IN (1,2)",
+		"SELECT `a` FROM `b` WHERE `c` = 'it\\'s'",
+		"DELETE FROM `b` WHERE `c` = 3",
+		"DELETE FROM `b` -- don't use ?\nWHERE `c` = 4",
+	}
+	if len(fake.queries) != len(want) {
+		t.Fatalf("got queries %q, want %q", fake.queries, want)
+	}
+	for i, q := range fake.queries {
+		if q != want[i] {
+			t.Errorf("\ngot: %v\nwant: %v", q, want[i])
+		}
+	}
+
+	if _, err := db.Exec("SELECT ?, ?", 1); err == nil {
+		t.Error("expected an argument mismatch error")
+	}
+}
@@ -2,6 +2,7 @@ package ql
 
 import (
 	"errors"
+	"fmt"
 )
 
 var (
@@ -12,4 +13,23 @@ var (
 	ErrInvalidValue       = errors.New("trying to interpolate invalid value into query")
 	ErrArgumentMismatch   = errors.New("mismatch between ? (placeholders) and arguments")
 	ErrInvalidSyntax      = errors.New("SQL syntax error")
+	ErrExecutableComment  = errors.New("executable comment not allowed in safe mode")
+	ErrNoTable            = errors.New("no table specified")
+	ErrNoColumns          = errors.New("no columns specified")
+	ErrNoValues           = errors.New("no values or records specified")
+	ErrNoSetClauses       = errors.New("no set clauses specified")
+	ErrNoSelects          = errors.New("no select statements specified")
+	ErrInvalidCursor      = errors.New("invalid cursor")
+	ErrNullCursorValue    = errors.New("cursor value is NULL")
 )
+
+// LiteralError is returned in the safe mode (see Connection.SafeMode) if an SQL
+// fragment contains a string or a numeric literal.
+type LiteralError struct {
+	Sql     string // the offending SQL fragment
+	Literal string // the first literal found
+}
+
+func (e *LiteralError) Error() string {
+	return fmt.Sprintf("literal %s not allowed in safe mode, use a placeholder instead: %q", e.Literal, e.Sql)
+}
@@ -14,19 +14,19 @@ type executor struct {
 // Exec executes the query. It returns the raw database/sql Result and an error if there
 // is one.
 func (e executor) Exec() (sql.Result, error) {
-	fullSql, err := Preprocess(e.builder.ToSql())
+	fullSql, logSql, err := preprocess(e.builder)
 	if err != nil {
-		return nil, e.EventErrKv("ql.exec.interpolate", err, kvs{"sql": fullSql})
+		return nil, e.EventErrKv("ql.exec.interpolate", err, kvs{"sql": logSql})
 	}
 
 	startTime := time.Now()
 	defer func() {
-		e.TimingKv("ql.exec", time.Since(startTime).Nanoseconds(), kvs{"sql": fullSql})
+		e.TimingKv("ql.exec", time.Since(startTime).Nanoseconds(), kvs{"sql": logSql, "fingerprint": Fingerprint(logSql)})
 	}()
 
 	result, err := e.runner.Exec(fullSql)
 	if err != nil {
-		return result, e.EventErrKv("ql.exec.exec", err, kvs{"sql": fullSql})
+		return result, e.EventErrKv("ql.exec.exec", err, kvs{"sql": logSql})
 	}
 
 	return result, nil
@@ -40,3 +40,31 @@ func (e executor) MustExec() sql.Result {
 	}
 	return res
 }
+
+// modifier executes an INSERT, UPDATE, or DELETE statement. The rows
+// returned by its RETURNING clause can be loaded like those of a SELECT
+// statement.
+type modifier struct {
+	loader
+}
+
+// newModifier returns a modifier whose events are named with the prefix,
+// e.g. ql.insert.
+func newModifier(e EventReceiver, r runner, events string) modifier {
+	return modifier{loader{EventReceiver: e, runner: r, events: events}}
+}
+
+func (m modifier) executor() executor {
+	return executor{EventReceiver: m.EventReceiver, runner: m.runner, builder: m.builder}
+}
+
+// Exec executes the query. It returns the raw database/sql Result and an error if there
+// is one.
+func (m modifier) Exec() (sql.Result, error) {
+	return m.executor().Exec()
+}
+
+// MustExec is like Exec but panics on error.
+func (m modifier) MustExec() sql.Result {
+	return m.executor().MustExec()
+}
@@ -1,5 +1,7 @@
 package ql
 
+import "errors"
+
 type expr struct {
 	Sql    string
 	Values []interface{}
@@ -9,3 +11,35 @@ type expr struct {
 func Expr(sql string, values ...interface{}) *expr {
 	return &expr{Sql: sql, Values: values}
 }
+
+func (e *expr) toExpr() (*expr, error) {
+	return e, nil
+}
+
+// As returns the expression aliased as alias to be used as a column of
+// Select, e.g.
+//
+//	conn.Select("id", ql.Expr("IF([score] > ?, 'hi', 'lo')", 50).As("band"))
+func (e *expr) As(alias string) *aliasedExpr {
+	return &aliasedExpr{e: e, alias: alias}
+}
+
+// aliasedExpr is an expression aliased by As. It is not an expression, as it
+// can only be a column of Select.
+type aliasedExpr struct {
+	e     expression
+	alias string
+}
+
+var errAliasedExpr = errors.New("an expression aliased by As can only be a column of Select")
+
+type defaultValue struct{}
+
+// Default is a value of InsertBuilder's Values, Pair, or of a field of
+// a record passed to Record, which inserts the default value of the column.
+var Default defaultValue
+
+// expression is an SQL fragment with args, i.e. an Expr or a CASE expression.
+type expression interface {
+	toExpr() (*expr, error)
+}
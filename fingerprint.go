@@ -0,0 +1,107 @@
+package ql
+
+import (
+	"bytes"
+	"strings"
+)
+
+// Fingerprint normalises the SQL statement so that statements differing only
+// in values are the same. String and numeric literals (including negative
+// numbers) are replaced by ?, IN lists by IN (?), comments are removed, and
+// white space is collapsed. E.g. both
+//
+//	SELECT * FROM user WHERE id IN (1, 2) AND name = 'bob'
+//	SELECT * FROM user  WHERE id IN (3) AND name = 'alice'
+//
+// result in
+//
+//	SELECT * FROM user WHERE id IN (?) AND name = ?
+//
+// If the SQL cannot be tokenized, an empty string is returned, as the SQL
+// could contain values which must not be logged.
+func Fingerprint(sql string) string {
+	toks, err := lex(sql)
+	if err != nil {
+		return ""
+	}
+
+	buf := new(bytes.Buffer)
+	space := false
+	var prev token // the last token written
+	for i := 0; i < len(toks); i++ {
+		t := toks[i]
+		switch t.kind {
+		case tokSpace, tokComment:
+			space = buf.Len() > 0
+			continue
+		case tokString, tokNumber:
+			t.text = "?"
+		case tokWord:
+			if j, ok := skipInList(toks, i); ok {
+				t.text = "IN (?)"
+				i = j
+			}
+		case tokOther:
+			if t.text == "-" && i+1 < len(toks) && toks[i+1].kind == tokNumber && !isOperand(prev) {
+				// a negative number
+				t = token{tokNumber, "?"}
+				i++
+			}
+		}
+		if space {
+			buf.WriteRune(' ')
+			space = false
+		}
+		buf.WriteString(t.text)
+		prev = t
+	}
+	return buf.String()
+}
+
+// operatorKeywords are the keywords after which a - is a unary minus.
+var operatorKeywords = map[string]bool{
+	"AND": true, "OR": true, "NOT": true, "XOR": true, "SELECT": true, "WHERE": true,
+	"HAVING": true, "ON": true, "SET": true, "WHEN": true, "THEN": true, "ELSE": true,
+	"BETWEEN": true, "LIKE": true, "IS": true, "IN": true, "BY": true, "LIMIT": true,
+	"OFFSET": true, "VALUES": true, "RETURN": true,
+}
+
+// isOperand reports whether t is an operand, in which case a following -
+// is a binary minus.
+func isOperand(t token) bool {
+	switch t.kind {
+	case tokNumber, tokString, tokIdent, tokPlaceholder:
+		return true
+	case tokWord:
+		return !operatorKeywords[strings.ToUpper(t.text)]
+	}
+	return t.text == ")"
+}
+
+// skipInList reports whether toks[i] starts an IN list consisting only of
+// values and placeholders. If so, it returns the index of the closing
+// parenthesis.
+func skipInList(toks []token, i int) (int, bool) {
+	if !strings.EqualFold(toks[i].text, "IN") {
+		return 0, false
+	}
+	i++
+	for i < len(toks) && (toks[i].kind == tokSpace || toks[i].kind == tokComment) {
+		i++
+	}
+	if i == len(toks) || toks[i].text != "(" {
+		return 0, false
+	}
+	for i++; i < len(toks); i++ {
+		switch t := toks[i]; {
+		case t.kind == tokString, t.kind == tokNumber, t.kind == tokPlaceholder,
+			t.kind == tokSpace, t.kind == tokComment, t.text == ",", t.text == "-":
+		case t.text == ")":
+			return i, true
+		default:
+			// e.g. a subquery or an expression
+			return 0, false
+		}
+	}
+	return 0, false
+}
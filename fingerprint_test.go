@@ -0,0 +1,63 @@
+package ql
+
+import (
+	"testing"
+
+	"github.com/mibk/ql/dialect"
+)
+
+func TestFingerprint(t *testing.T) {
+	tests := []struct {
+		sql string
+		exp string
+	}{
+		{"SELECT * FROM x WHERE a = 1 AND b = 'wat'", "SELECT * FROM x WHERE a = ? AND b = ?"},
+		{"SELECT  *\n\tFROM x -- comment\n WHERE a = 1.5e3 /* c */", "SELECT * FROM x WHERE a = ?"},
+		{`SELECT * FROM x WHERE a = 'it\'s' AND b = "x""y"`, "SELECT * FROM x WHERE a = ? AND b = ?"},
+		{"SELECT * FROM x WHERE a IN (1,2,-3) AND b in ('a', 'b')", "SELECT * FROM x WHERE a IN (?) AND b IN (?)"},
+		{"SELECT * FROM x WHERE a IN (SELECT id FROM y WHERE z = 2)",
+			"SELECT * FROM x WHERE a IN (SELECT id FROM y WHERE z = ?)"},
+		{"SELECT `a1`, t2.b FROM `x` LIMIT 10 OFFSET 20", "SELECT `a1`, t2.b FROM `x` LIMIT ? OFFSET ?"},
+		{"SELECT 'unterminated", ""},
+		{"SELECT * FROM x WHERE a = -5 AND b > -1.5 OR c = -(3)", "SELECT * FROM x WHERE a = ? AND b > ? OR c = -(?)"},
+		{"SELECT a -1, a - -1, (2)-1 FROM x WHERE a BETWEEN -2 AND -1", "SELECT a -?, a - ?, (?)-? FROM x WHERE a BETWEEN ? AND ?"},
+	}
+
+	for _, test := range tests {
+		if got := Fingerprint(test.sql); got != test.exp {
+			t.Errorf("\ngot: %v\nwant: %v", got, test.exp)
+		}
+	}
+
+	s := createFakeConnection()
+	f1 := s.Select("a").From("b").Where(And{"id": []int{1, 2}}).Fingerprint()
+	f2 := s.Select("a").From("b").Where(And{"id": []int{3}}).Fingerprint()
+	if f1 != f2 || f1 != "SELECT a FROM b WHERE (`id` IN (?))" {
+		t.Errorf("got different fingerprints: %v, %v", f1, f2)
+	}
+
+	f1 = s.Select("a").From("b").Where("x = ?", -5).Fingerprint()
+	f2 = s.Select("a").From("b").Where("x = ?", 5).Fingerprint()
+	if f1 != f2 {
+		t.Errorf("got different fingerprints: %v, %v", f1, f2)
+	}
+
+	defer func(d Dialect) { D = d }(D)
+	D = dialect.Postgres{}
+	// a backslash is not an escape character in PostgreSQL
+	if f := s.Select("a").From("b").Where("x = ?", `c:\`).Fingerprint(); f != `SELECT a FROM b WHERE ("x" = ?)` {
+		t.Errorf("got fingerprint: %v", f)
+	}
+}
+
+func TestFingerprintRedacted(t *testing.T) {
+	s := createFakeConnection()
+
+	_, logSql, err := preprocess(s.Update("u").Set("pw", Secret("hunter2")).Where("id = ?", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := Fingerprint(logSql); f != "UPDATE u SET `pw` = ? WHERE (`id` = ?)" {
+		t.Errorf("got fingerprint: %v", f)
+	}
+}
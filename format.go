@@ -0,0 +1,149 @@
+package ql
+
+import (
+	"bytes"
+	"strings"
+)
+
+// clauseKeywords are the keywords starting a new line in formatted SQL.
+// Longer keywords must precede their prefixes.
+var clauseKeywords = [][]string{
+	{"SELECT"}, {"FROM"}, {"WHERE"}, {"HAVING"}, {"LIMIT"}, {"SET"},
+	{"VALUES"}, {"UNION", "ALL"}, {"UNION"}, {"INTERSECT"}, {"EXCEPT"},
+	{"GROUP", "BY"}, {"ORDER", "BY"}, {"INSERT", "INTO"}, {"DELETE"}, {"UPDATE"},
+	{"NATURAL", "JOIN"}, {"CROSS", "JOIN"}, {"INNER", "JOIN"}, {"STRAIGHT_JOIN"},
+	{"LEFT", "OUTER", "JOIN"}, {"LEFT", "JOIN"}, {"RIGHT", "OUTER", "JOIN"},
+	{"RIGHT", "JOIN"}, {"FULL", "OUTER", "JOIN"}, {"FULL", "JOIN"}, {"JOIN"},
+	{"ON", "DUPLICATE", "KEY", "UPDATE"}, {"ON", "CONFLICT"}, {"RETURNING"},
+	{"WITH", "RECURSIVE"}, {"WITH"}, {"WINDOW"}, {"FOR", "UPDATE"}, {"FOR", "SHARE"},
+	{"LOCK", "IN", "SHARE", "MODE"},
+}
+
+// Format lays out the SQL statement on multiple lines for better readability.
+// The main clauses (SELECT, FROM, JOIN, WHERE, GROUP BY, ...) start on a new
+// line, their contents are indented, and subqueries are indented one more
+// level. String literals and identifiers are kept intact, other white space
+// is collapsed. If the SQL cannot be tokenized, it is returned unchanged.
+func Format(sql string) string {
+	toks, err := lex(sql)
+	if err != nil {
+		return sql
+	}
+
+	buf := new(bytes.Buffer)
+	depth := 0          // parenthesis depth
+	var subs []subquery // enclosing subqueries
+	base := 0           // indentation of clause keywords
+	line := 0           // indentation of the current line
+	pending := -1       // indentation of a pending new line, or -1
+	space := false      // whether a space precedes the next token
+
+	for i := 0; i < len(toks); i++ {
+		t := toks[i]
+		if t.kind == tokSpace {
+			space = true
+			continue
+		}
+
+		n := 0
+		if t.kind == tokWord && depth == queryDepth(subs) {
+			// not inside parentheses of a function call or an expression,
+			// e.g. EXTRACT(YEAR FROM d)
+			n = matchClause(toks[i:])
+		}
+		if t.text == ")" {
+			if k := len(subs); k > 0 && subs[k-1].depth == depth {
+				base, pending = subs[k-1].base, subs[k-1].line
+				subs = subs[:k-1]
+			}
+			depth--
+		}
+
+		switch {
+		case n > 0:
+			pending = base
+		case pending >= 0:
+		case space:
+			buf.WriteRune(' ')
+		}
+		if pending >= 0 {
+			if buf.Len() > 0 {
+				buf.WriteRune('\n')
+			}
+			buf.WriteString(strings.Repeat("\t", pending))
+			line, pending = pending, -1
+		}
+		space = false
+
+		if n > 0 {
+			for j := i; j < i+n; j++ {
+				if toks[j].kind == tokSpace {
+					buf.WriteRune(' ')
+				} else {
+					buf.WriteString(toks[j].text)
+				}
+			}
+			i += n - 1
+			pending = base + 1
+			continue
+		}
+
+		buf.WriteString(t.text)
+		switch {
+		case t.text == "(":
+			depth++
+			j := i + 1
+			for j < len(toks) && toks[j].kind == tokSpace {
+				j++
+			}
+			if matchClause(toks[j:]) > 0 {
+				subs = append(subs, subquery{depth: depth, base: base, line: line})
+				base = line + 1
+			}
+		case t.kind == tokComment && !strings.HasPrefix(t.text, "/*"):
+			pending = line
+		}
+	}
+	return buf.String()
+}
+
+type subquery struct {
+	depth int // parenthesis depth inside the subquery
+	base  int // base indentation outside the subquery
+	line  int // indentation of the line with the opening parenthesis
+}
+
+// queryDepth returns the parenthesis depth of the innermost subquery.
+func queryDepth(subs []subquery) int {
+	if len(subs) == 0 {
+		return 0
+	}
+	return subs[len(subs)-1].depth
+}
+
+// matchClause returns the number of tokens (including white space) forming
+// a clause keyword at the beginning of toks, or 0.
+func matchClause(toks []token) int {
+Keywords:
+	for _, kw := range clauseKeywords {
+		i := 0
+		for j, w := range kw {
+			if j > 0 {
+				if i == len(toks) || toks[i].kind != tokSpace {
+					continue Keywords
+				}
+				i++
+			}
+			if i == len(toks) || toks[i].kind != tokWord || !strings.EqualFold(toks[i].text, w) {
+				continue Keywords
+			}
+			i++
+		}
+		if i < len(toks) && toks[i].text == "(" {
+			// a function, e.g. VALUES(col)
+			continue
+		}
+		return i
+	}
+	return 0
+}
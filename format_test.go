@@ -0,0 +1,32 @@
+package ql
+
+import "testing"
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		sql string
+		exp string
+	}{
+		{"SELECT a, COUNT(*) AS n FROM `user` u LEFT JOIN `post` p ON (p.user_id = u.id) " +
+			"WHERE (u.name = 'a  FROM b') AND (u.id IN (SELECT id FROM x WHERE y = 1)) " +
+			"GROUP BY a ORDER BY n DESC LIMIT 10",
+			"SELECT\n\ta, COUNT(*) AS n\nFROM\n\t`user` u\nLEFT JOIN\n\t`post` p ON (p.user_id = u.id)\n" +
+				"WHERE\n\t(u.name = 'a  FROM b') AND (u.id IN (\n" +
+				"\t\tSELECT\n\t\t\tid\n\t\tFROM\n\t\t\tx\n\t\tWHERE\n\t\t\ty = 1\n\t))\n" +
+				"GROUP BY\n\ta\nORDER BY\n\tn DESC\nLIMIT\n\t10"},
+		{"INSERT INTO a (`b`,`c`) VALUES (1,2),(3,4) ON DUPLICATE KEY UPDATE b = VALUES(b)",
+			"INSERT INTO\n\ta (`b`,`c`)\nVALUES\n\t(1,2),(3,4)\nON DUPLICATE KEY UPDATE\n\tb = VALUES(b)"},
+		{"select *\n  from t -- comment\nwhere a in (1, 2)",
+			"select\n\t*\nfrom\n\tt -- comment\nwhere\n\ta in (1, 2)"},
+		{"SELECT 'unterminated", "SELECT 'unterminated"},
+		{"SELECT SUBSTRING(name FROM 2), EXTRACT(YEAR FROM d) FROM t WHERE (a IN (SELECT TRIM(LEADING 'x' FROM b) FROM c))",
+			"SELECT\n\tSUBSTRING(name FROM 2), EXTRACT(YEAR FROM d)\nFROM\n\tt\nWHERE\n\t(a IN (\n" +
+				"\t\tSELECT\n\t\t\tTRIM(LEADING 'x' FROM b)\n\t\tFROM\n\t\t\tc\n\t))"},
+	}
+
+	for _, test := range tests {
+		if got := Format(test.sql); got != test.exp {
+			t.Errorf("\ngot: %v\nwant: %v", got, test.exp)
+		}
+	}
+}
@@ -2,25 +2,41 @@ package ql
 
 import (
 	"bytes"
+	"errors"
 	"reflect"
+
+	"github.com/mibk/ql/query"
 )
 
 // InsertBuilder contains the clauses for an INSERT statement.
 type InsertBuilder struct {
-	executor
+	modifier // Exec and methods for loading the returned rows
 
+	Mode query.InsertMode
 	Into string
 	Cols []string
 	Vals [][]interface{}
 	Recs []interface{}
+
+	// Select is the source of the inserted rows instead of Vals and Recs.
+	Select *SelectBuilder
+
+	ConflictTarget    []string
+	ConflictSets      []*setClause
+	ConflictDoNothing bool
+
+	ReturningColumns []string
+
+	buildState
 }
 
-func newInsertBuilder(e EventReceiver, r runner, into string) *InsertBuilder {
+func newInsertBuilder(c *Connection, r runner, into string) *InsertBuilder {
 	b := &InsertBuilder{
-		executor: executor{EventReceiver: e, runner: r},
-		Into:     into,
+		modifier:   newModifier(c, r, "ql.insert"),
+		Into:       into,
+		buildState: buildState{safe: c.SafeMode},
 	}
-	b.executor.builder = b
+	b.loader.builder = b
 	return b
 }
 
@@ -34,6 +50,29 @@ func (tx *Tx) InsertInto(into string) *InsertBuilder {
 	return newInsertBuilder(tx.Connection, tx.Tx, into)
 }
 
+// ReplaceInto instantiates a InsertBuilder for a REPLACE statement, which
+// deletes the rows conflicting with the inserted ones first.
+func (db *Connection) ReplaceInto(into string) *InsertBuilder {
+	return newInsertBuilder(db, db.DB, into).mode(query.Replace)
+}
+
+// ReplaceInto instantiates a InsertBuilder for a REPLACE statement bound to
+// a transaction.
+func (tx *Tx) ReplaceInto(into string) *InsertBuilder {
+	return newInsertBuilder(tx.Connection, tx.Tx, into).mode(query.Replace)
+}
+
+func (b *InsertBuilder) mode(m query.InsertMode) *InsertBuilder {
+	b.Mode = m
+	return b
+}
+
+// Ignore makes the statement skip the rows conflicting with the existing
+// ones (INSERT IGNORE in MySQL, ON CONFLICT DO NOTHING in Postgres).
+func (b *InsertBuilder) Ignore() *InsertBuilder {
+	return b.mode(query.InsertIgnore)
+}
+
 // Columns appends columns to insert in the statement.
 func (b *InsertBuilder) Columns(columns ...string) *InsertBuilder {
 	b.Cols = columns
@@ -42,7 +81,7 @@ func (b *InsertBuilder) Columns(columns ...string) *InsertBuilder {
 
 // Values appends a set of values to the statement.
 func (b *InsertBuilder) Values(vals ...interface{}) *InsertBuilder {
-	b.Vals = append(b.Vals, vals)
+	b.Vals = append(b.Vals, b.values(vals))
 	return b
 }
 
@@ -54,6 +93,7 @@ func (b *InsertBuilder) Record(record interface{}) *InsertBuilder {
 
 // Pair adds a key/value pair to the statement.
 func (b *InsertBuilder) Pair(column string, value interface{}) *InsertBuilder {
+	value = b.value(value)
 	b.Cols = append(b.Cols, column)
 	lenVals := len(b.Vals)
 	if lenVals == 0 {
@@ -62,55 +102,173 @@ func (b *InsertBuilder) Pair(column string, value interface{}) *InsertBuilder {
 	} else if lenVals == 1 {
 		b.Vals[0] = append(b.Vals[0], value)
 	} else {
-		panic("pair only allows you to specify 1 record to insret")
+		b.fail(errors.New("pair only allows you to specify 1 record to insert"))
+	}
+	return b
+}
+
+// FromSelect inserts the rows returned by the SELECT statement, whose
+// columns match Columns, instead of Values or Records.
+func (b *InsertBuilder) FromSelect(sel *SelectBuilder) *InsertBuilder {
+	b.Select = sel
+	return b
+}
+
+// OnConflict sets the columns of the unique index whose violation is handled
+// by DoUpdate, DoUpdateSet, or DoNothing. MySQL ignores the target.
+func (b *InsertBuilder) OnConflict(target ...string) *InsertBuilder {
+	b.ConflictTarget = target
+	return b
+}
+
+// DoUpdate updates the columns of the conflicting row to the values being
+// inserted; i.e. col = VALUES(col) in MySQL, or col = EXCLUDED.col in Postgres.
+func (b *InsertBuilder) DoUpdate(columns ...string) *InsertBuilder {
+	for _, col := range columns {
+		b.ConflictSets = append(b.ConflictSets, &setClause{column: col, value: insertedValue(col)})
 	}
 	return b
 }
 
-// ToSql serialized the InsertBuilder to a SQL string.  It returns the string with
-// placeholders and a slice of query arguments.
+// DoUpdateSet updates the column of the conflicting row to the value, which
+// can be an Expr or a CASE expression, e.g.
+//
+//	b.DoUpdateSet("counter", ql.Expr("[counter] + 1"))
+func (b *InsertBuilder) DoUpdateSet(column string, value interface{}) *InsertBuilder {
+	value = b.value(value)
+	b.ConflictSets = append(b.ConflictSets, &setClause{column: column, value: value})
+	return b
+}
+
+// DoNothing keeps the conflicting rows as they are.
+func (b *InsertBuilder) DoNothing() *InsertBuilder {
+	b.ConflictDoNothing = true
+	return b
+}
+
+// OnDuplicateKeyUpdate is a shorthand for DoUpdate.
+func (b *InsertBuilder) OnDuplicateKeyUpdate(columns ...string) *InsertBuilder {
+	return b.DoUpdate(columns...)
+}
+
+// Returning sets the columns (or expressions) of the affected rows returned
+// by the statement, which can be loaded using All or One. It is supported by
+// dialect.Postgres and dialect.MariaDB.
+func (b *InsertBuilder) Returning(columns ...string) *InsertBuilder {
+	b.ReturningColumns = columns
+	return b
+}
+
+// Clone returns a deep copy of the builder, which can be modified without
+// affecting b. Subqueries are shared.
+func (b *InsertBuilder) Clone() *InsertBuilder {
+	c := *b
+	c.Cols = cloneStrings(b.Cols)
+	c.Vals = nil
+	for _, row := range b.Vals {
+		c.Vals = append(c.Vals, cloneValues(row))
+	}
+	c.Recs = cloneValues(b.Recs)
+	c.ConflictTarget = cloneStrings(b.ConflictTarget)
+	c.ConflictSets = cloneSetClauses(b.ConflictSets)
+	c.ReturningColumns = cloneStrings(b.ReturningColumns)
+	c.loader.builder = &c
+	return &c
+}
+
+// writeRow writes the row of values like "(?,?,?)" and adds the values to
+// args. An Expr (see buildState.value) is written as is, and Default as
+// DEFAULT.
+func writeRow(w query.Writer, row []interface{}, args *[]interface{}) error {
+	w.WriteRune('(')
+	for i, v := range row {
+		if i > 0 {
+			w.WriteRune(',')
+		}
+		switch v := v.(type) {
+		case defaultValue:
+			w.WriteString("DEFAULT")
+		case *expr:
+			sql, vals, err := expandSubqueries(v.Sql, v.Values)
+			if err != nil {
+				return err
+			}
+			w.WriteString(sql)
+			*args = append(*args, vals...)
+		default:
+			w.WriteRune('?')
+			*args = append(*args, v)
+		}
+	}
+	w.WriteRune(')')
+	return nil
+}
+
+// ToSql serialized the InsertBuilder to a SQL string. It returns the string with
+// placeholders and a slice of query arguments. It returns an empty string if
+// the statement cannot be built; see ToSqlErr.
 func (b *InsertBuilder) ToSql() (string, []interface{}) {
+	sql, args, _ := b.ToSqlErr()
+	return sql, args
+}
+
+// ToSqlErr is like ToSql, but it also returns the first error that occurred
+// while building the statement.
+func (b *InsertBuilder) ToSqlErr() (string, []interface{}, error) {
+	if b.err != nil {
+		return "", nil, b.err
+	}
 	if len(b.Into) == 0 {
-		panic("no table specified")
+		return "", nil, ErrNoTable
 	}
 	if len(b.Cols) == 0 {
-		panic("no columns specified")
+		return "", nil, ErrNoColumns
+	}
+	if b.Select == nil && len(b.Vals) == 0 && len(b.Recs) == 0 {
+		return "", nil, ErrNoValues
 	}
-	if len(b.Vals) == 0 && len(b.Recs) == 0 {
-		panic("no values or records specified")
+	if b.Select != nil && (len(b.Vals) > 0 || len(b.Recs) > 0) {
+		return "", nil, errors.New("values or records cannot be combined with a select")
+	}
+	if b.Mode == query.Replace && (len(b.ConflictTarget) > 0 || b.ConflictDoNothing || len(b.ConflictSets) > 0) {
+		return "", nil, errors.New("REPLACE cannot be combined with an upsert clause")
 	}
 
 	sql := new(bytes.Buffer)
-	var placeholder bytes.Buffer // Build the placeholder like "(?,?,?)"
 	var args []interface{}
 
-	sql.WriteString("INSERT INTO ")
+	if err := D.ApplyInsert(sql, b.Mode); err != nil {
+		return "", nil, err
+	}
 	sql.WriteString(b.Into)
 	sql.WriteString(" (")
 
-	// Simulataneously write the cols to the sql buffer, and build a placeholder
-	placeholder.WriteRune('(')
 	for i, c := range b.Cols {
 		if i > 0 {
 			sql.WriteRune(',')
-			placeholder.WriteRune(',')
 		}
 		D.EscapeIdent(sql, c)
-		placeholder.WriteRune('?')
 	}
-	sql.WriteString(") VALUES ")
-	placeholder.WriteRune(')')
-	placeholderStr := placeholder.String()
+	sql.WriteString(")")
+
+	if b.Select != nil {
+		selSql, selArgs, err := b.Select.ToSqlErr()
+		if err != nil {
+			return "", nil, err
+		}
+		sql.WriteString(" " + selSql)
+		args = append(args, selArgs...)
+	} else {
+		sql.WriteString(" VALUES ")
+	}
 
 	// Go thru each value we want to insert. Write the placeholders, and collect args
 	for i, row := range b.Vals {
 		if i > 0 {
 			sql.WriteRune(',')
 		}
-		sql.WriteString(placeholderStr)
-
-		for _, v := range row {
-			args = append(args, v)
+		if err := writeRow(sql, row, &args); err != nil {
+			return "", nil, err
 		}
 	}
 	anyVals := len(b.Vals) > 0
@@ -120,17 +278,43 @@ func (b *InsertBuilder) ToSql() (string, []interface{}) {
 		if i > 0 || anyVals {
 			sql.WriteRune(',')
 		}
-		sql.WriteString(placeholderStr)
 
 		ind := reflect.Indirect(reflect.ValueOf(rec))
 		vals, err := valuesFor(ind.Type(), ind, b.Cols)
 		if err != nil {
-			panic(err.Error())
+			return "", nil, err
 		}
-		for _, v := range vals {
-			args = append(args, v)
+		state := b.buildState // the records are only read here
+		if vals = state.values(vals); state.err != nil {
+			return "", nil, state.err
 		}
+		if err := writeRow(sql, vals, &args); err != nil {
+			return "", nil, err
+		}
+	}
+
+	ignore := b.Mode == query.InsertIgnore
+	doNothing := b.ConflictDoNothing || ignore && len(b.ConflictSets) == 0
+	if doNothing || len(b.ConflictSets) > 0 {
+		err := D.ApplyUpsert(sql, query.Upsert{
+			Target:    b.ConflictTarget,
+			DoNothing: doNothing,
+			Columns:   b.Cols,
+			Ignore:    ignore,
+		})
+		if err != nil {
+			return "", nil, err
+		}
+		if !doNothing {
+			if err := writeSetClauses(sql, b.ConflictSets, &args); err != nil {
+				return "", nil, err
+			}
+		}
+	}
+
+	if err := buildReturning(sql, query.InsertStmt, b.ReturningColumns); err != nil {
+		return "", nil, err
 	}
 
-	return sql.String(), args
+	return sql.String(), args, nil
 }
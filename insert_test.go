@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"testing"
 
+	"github.com/mibk/ql/dialect"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -73,6 +74,20 @@ func TestInsertKeywordColumnName(t *testing.T) {
 	assert.Equal(t, rowsAff, int64(1))
 }
 
+func TestInsertSensitiveRecord(t *testing.T) {
+	s := createFakeConnection()
+
+	type user struct {
+		Name     string
+		Password string `db:"pass,sensitive"`
+	}
+	b := s.InsertInto("a").Columns("name", "pass").Record(user{"bob", "s3cr3t"}).Values("eve", Secret("pa$$"))
+	sql, logSql, err := preprocess(b)
+	assert.NoError(t, err)
+	assert.Equal(t, sql, "INSERT INTO a (`name`,`pass`) VALUES ('eve','pa$$'),('bob','s3cr3t')")
+	assert.Equal(t, logSql, "INSERT INTO a (`name`,`pass`) VALUES ('eve','[redacted]'),('bob','[redacted]')")
+}
+
 func TestInsertReal(t *testing.T) {
 	// Insert by specifying values
 	s := createRealConnectionWithFixtures()
@@ -114,3 +129,149 @@ func validateInsertingBarack(t *testing.T, s *Connection, res sql.Result, err er
 }
 
 // TODO: do a real test inserting multiple records
+
+func TestInsertUpsertToSql(t *testing.T) {
+	s := createFakeConnection()
+
+	sql, args := s.InsertInto("a").Columns("id", "name", "counter").Values(1, "x", 1).
+		OnDuplicateKeyUpdate("name").DoUpdateSet("counter", Expr("[counter] + ?", 1)).ToSql()
+	assert.Equal(t, sql, "INSERT INTO a (`id`,`name`,`counter`) VALUES (?,?,?)"+
+		" ON DUPLICATE KEY UPDATE `name` = VALUES(`name`), `counter` = [counter] + ?")
+	assert.Equal(t, args, []interface{}{1, "x", 1, 1})
+
+	sql, _ = s.InsertInto("a").Columns("id", "name").Values(1, "x").OnConflict("id").DoNothing().ToSql()
+	assert.Equal(t, sql, "INSERT INTO a (`id`,`name`) VALUES (?,?) ON DUPLICATE KEY UPDATE `id` = `id`")
+
+	defer func(d Dialect) { D = d }(D)
+	D = dialect.Postgres{}
+
+	sql, args = s.InsertInto("a").Columns("id", "name").Values(1, "x").
+		OnConflict("id").DoUpdate("name").DoUpdateSet("updated", true).ToSql()
+	assert.Equal(t, sql, `INSERT INTO a ("id","name") VALUES (?,?)`+
+		` ON CONFLICT ("id") DO UPDATE SET "name" = EXCLUDED."name", "updated" = ?`)
+	assert.Equal(t, args, []interface{}{1, "x", true})
+
+	str := s.InsertInto("a").Columns("id", "name").Values(1, `x"y`).
+		OnConflict("id").DoUpdate("name").DoUpdateSet("updated", true).String()
+	assert.Equal(t, str, `INSERT INTO a ("id","name") VALUES (1,'x"y')`+
+		` ON CONFLICT ("id") DO UPDATE SET "name" = EXCLUDED."name", "updated" = TRUE`)
+
+	sql, _ = s.InsertInto("a").Columns("id").Values(1).DoNothing().ToSql()
+	assert.Equal(t, sql, `INSERT INTO a ("id") VALUES (?) ON CONFLICT DO NOTHING`)
+
+	_, _, err := s.InsertInto("a").Columns("id", "name").Values(1, "x").DoUpdate("name").ToSqlErr()
+	assert.Equal(t, err, &dialect.UnsupportedError{Dialect: "PostgreSQL", Feature: "ON CONFLICT DO UPDATE without a conflict target"})
+}
+
+func TestInsertIgnoreAndReplaceToSql(t *testing.T) {
+	s := createFakeConnection()
+
+	sql, args := s.InsertInto("a").Ignore().Columns("b", "c").Values(1, 2).ToSql()
+	assert.Equal(t, sql, "INSERT IGNORE INTO a (`b`,`c`) VALUES (?,?)")
+	assert.Equal(t, args, []interface{}{1, 2})
+
+	sql, args = s.ReplaceInto("a").Pair("b", 1).Pair("c", 2).ToSql()
+	assert.Equal(t, sql, "REPLACE INTO a (`b`,`c`) VALUES (?,?)")
+	assert.Equal(t, args, []interface{}{1, 2})
+
+	_, _, err := s.ReplaceInto("a").Pair("b", 1).OnDuplicateKeyUpdate("b").ToSqlErr()
+	assert.Equal(t, err.Error(), "REPLACE cannot be combined with an upsert clause")
+
+	defer func(d Dialect) { D = d }(D)
+	D = dialect.Postgres{}
+
+	sql, _ = s.InsertInto("a").Ignore().Columns("b").Values(1).ToSql()
+	assert.Equal(t, sql, `INSERT INTO a ("b") VALUES (?) ON CONFLICT DO NOTHING`)
+	assert.Equal(t, s.InsertInto("a").Ignore().Columns("b").Values(1).String(),
+		`INSERT INTO a ("b") VALUES (1) ON CONFLICT DO NOTHING`)
+
+	_, _, err = s.ReplaceInto("a").Columns("b").Values(1).ToSqlErr()
+	assert.Equal(t, err, &dialect.UnsupportedError{Dialect: "PostgreSQL", Feature: "REPLACE INTO"})
+}
+
+func TestInsertFromSelectToSql(t *testing.T) {
+	s := createFakeConnection()
+
+	sel := s.Select("id, name").From("users").Where("created < ?", 2015)
+	sql, args := s.InsertInto("archive").Columns("id", "name").FromSelect(sel).ToSql()
+	assert.Equal(t, sql, "INSERT INTO archive (`id`,`name`) SELECT id, name FROM users WHERE ([created] < ?)")
+	assert.Equal(t, args, []interface{}{2015})
+
+	sql, _ = s.InsertInto("archive").Ignore().Columns("id").FromSelect(s.Select("id").From("users")).ToSql()
+	assert.Equal(t, sql, "INSERT IGNORE INTO archive (`id`) SELECT id FROM users")
+
+	_, _, err := s.InsertInto("archive").Columns("id").Values(1).FromSelect(sel).ToSqlErr()
+	assert.NotNil(t, err)
+}
+
+func TestInsertReturningToSql(t *testing.T) {
+	s := createFakeConnection()
+
+	_, _, err := s.InsertInto("a").Columns("b").Values(1).Returning("id").ToSqlErr()
+	assert.Equal(t, err, &dialect.UnsupportedError{Dialect: "MySQL", Feature: "RETURNING"})
+
+	defer func(d Dialect) { D = d }(D)
+	D = dialect.MariaDB{}
+
+	sql, _ := s.InsertInto("a").Columns("b").Values(1).Returning("id", "created").ToSql()
+	assert.Equal(t, sql, "INSERT INTO a (`b`) VALUES (?) RETURNING id, created")
+
+	D = dialect.Postgres{}
+
+	sql, _ = s.InsertInto("a").Columns("b").Values(1).OnConflict("b").DoNothing().Returning("id").ToSql()
+	assert.Equal(t, sql, `INSERT INTO a ("b") VALUES (?) ON CONFLICT ("b") DO NOTHING RETURNING id`)
+
+	str := s.InsertInto("a").Columns("b").Values(1).OnConflict("b").DoNothing().Returning("[id]").String()
+	assert.Equal(t, str, `INSERT INTO a ("b") VALUES (1) ON CONFLICT ("b") DO NOTHING RETURNING "id"`)
+}
+
+func TestInsertClone(t *testing.T) {
+	s := createFakeConnection()
+
+	b := s.InsertInto("a").Pair("b", 1)
+	c := b.Clone().Pair("c", 2).DoUpdate("c")
+
+	sql, args := b.ToSql()
+	assert.Equal(t, sql, "INSERT INTO a (`b`) VALUES (?)")
+	assert.Equal(t, args, []interface{}{1})
+
+	sql, args = c.ToSql()
+	assert.Equal(t, sql, "INSERT INTO a (`b`,`c`) VALUES (?,?) ON DUPLICATE KEY UPDATE `c` = VALUES(`c`)")
+	assert.Equal(t, args, []interface{}{1, 2})
+}
+
+func TestInsertExprAndDefaultToSql(t *testing.T) {
+	s := createFakeConnection()
+
+	sql, args := s.InsertInto("a").Pair("b", 1).Pair("created", Expr("NOW()")).Pair("c", Default).ToSql()
+	assert.Equal(t, sql, "INSERT INTO a (`b`,`created`,`c`) VALUES (?,NOW(),DEFAULT)")
+	assert.Equal(t, args, []interface{}{1})
+
+	type rec struct {
+		Name    string
+		Created interface{}
+		Rank    interface{}
+	}
+	sql, args = s.InsertInto("a").Columns("name", "created", "rank").
+		Values("x", Expr("FROM_UNIXTIME(?)", 1435700000), Case().When("[x] > ?", 1).Then(2).Else(3)).
+		Record(&rec{"y", Default, Expr("[rank] + ?", 1)}).
+		Record(rec{"z", nil, 5}).ToSql()
+	assert.Equal(t, sql, "INSERT INTO a (`name`,`created`,`rank`) VALUES "+
+		"(?,FROM_UNIXTIME(?),CASE WHEN [x] > ? THEN ? ELSE ? END),(?,DEFAULT,[rank] + ?),(?,?,?)")
+	assert.Equal(t, args, []interface{}{"x", 1435700000, 1, 2, 3, "y", 1, "z", nil, 5})
+
+	sub := s.Select("MAX(rank)").From("a").Where("team = ?", 3)
+	sql, args = s.InsertInto("a").Pair("name", "x").Pair("rank", Expr("? + 1", sub)).ToSql()
+	assert.Equal(t, sql, "INSERT INTO a (`name`,`rank`) VALUES (?,(SELECT MAX(rank) FROM a WHERE ([team] = ?)) + 1)")
+	assert.Equal(t, args, []interface{}{"x", 3})
+
+	_, _, err := s.InsertInto("a").Columns("rank").Values(Case().Then(1)).ToSqlErr()
+	assert.Equal(t, err.Error(), "CASE: Then must follow When")
+
+	s.SafeMode = true
+	_, _, err = s.InsertInto("a").Pair("b", Expr("'x'")).ToSqlErr()
+	assert.IsType(t, err, &LiteralError{})
+
+	_, _, err = s.InsertInto("a").Columns("name", "created", "rank").Record(rec{"y", Expr("'x'"), 1}).ToSqlErr()
+	assert.IsType(t, err, &LiteralError{})
+}
@@ -0,0 +1,38 @@
+package ql
+
+import "github.com/mibk/ql/query"
+
+type joinClause struct {
+	Kind        string // e.g. "LEFT JOIN"
+	Table       string
+	Alias       string
+	OnFragments []*whereFragment
+}
+
+func (b *baseBuilder) join(kind, table, alias string, onExprOrMap interface{}, args []interface{}) {
+	j := &joinClause{Kind: kind, Table: table, Alias: alias}
+	if onExprOrMap != nil {
+		j.OnFragments = b.conditions(onExprOrMap, args)
+	}
+	b.JoinClauses = append(b.JoinClauses, j)
+}
+
+func (b *baseBuilder) buildJoins(w query.Writer, args *[]interface{}) error {
+	for _, j := range b.JoinClauses {
+		w.WriteString(" ")
+		w.WriteString(j.Kind)
+		w.WriteString(" ")
+		w.WriteString(j.Table)
+		if j.Alias != "" {
+			w.WriteString(" ")
+			D.EscapeIdent(w, j.Alias)
+		}
+		if len(j.OnFragments) > 0 {
+			w.WriteString(" ON ")
+			if err := writeWhereFragmentsToSql(w, j.OnFragments, args); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
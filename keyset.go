@@ -0,0 +1,241 @@
+package ql
+
+import (
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Seek orders the statement by the orderings and, if the values are given,
+// restricts it to the rows following the row with the values in that order
+// (keyset pagination). The orderings must identify the rows uniquely, e.g.
+// by ending with the primary key, and their columns must not be NULL.
+//
+//	b.Seek([]ql.Ordering{ql.Sort("created", ql.Desc), ql.Sort("id", ql.Asc)}, lastCreated, lastId)
+func (b *SelectBuilder) Seek(orders []Ordering, values ...interface{}) *SelectBuilder {
+	for _, o := range orders {
+		b.order([]Orderer{o})
+	}
+	if len(values) == 0 {
+		return b
+	}
+	if len(values) != len(orders) {
+		b.fail(errors.New("number of values does not match the number of orderings"))
+		return b
+	}
+	b.where(keysetCond(orders, values))
+	return b
+}
+
+// SeekCursor is like Seek, but the values are decoded from the cursor
+// returned by Cursor, NextCursor, or KeysetPage. An empty cursor selects
+// the first page.
+func (b *SelectBuilder) SeekCursor(orders []Ordering, cursor string) *SelectBuilder {
+	if cursor == "" {
+		return b.Seek(orders)
+	}
+	values, err := decodeCursor(cursor)
+	if err != nil {
+		b.fail(err)
+		return b
+	}
+	return b.Seek(orders, values...)
+}
+
+// KeysetPage loads up to perPage rows following the cursor (or the first
+// rows if empty) into the dest like All, and returns the cursor of the next
+// page, or an empty string if there are no more rows. The dest must be
+// a slice of pointers to structs with fields for the ordered columns, or
+// a slice of values if there is only one ordering. See Seek.
+func (b *SelectBuilder) KeysetPage(dest interface{}, orders []Ordering, cursor string, perPage uint64) (next string, err error) {
+	n, err := b.Clone().SeekCursor(orders, cursor).Limit(perPage).All(dest)
+	if err != nil || n == 0 || uint64(n) < perPage {
+		return "", err
+	}
+	rows := reflect.Indirect(reflect.ValueOf(dest))
+	return NextCursor(orders, rows.Index(rows.Len()-1).Interface())
+}
+
+// keysetCond returns the condition matching the rows following the values
+// in the order of the orderings. If all the orderings have the same
+// direction, a row comparison is used.
+func keysetCond(orders []Ordering, values []interface{}) *expr {
+	cmp := func(o Ordering) string {
+		if o.Desc {
+			return "[" + o.Column + "] < ?"
+		}
+		return "[" + o.Column + "] > ?"
+	}
+	if len(orders) == 1 {
+		return Expr(cmp(orders[0]), values[0])
+	}
+
+	sameDir := true
+	for _, o := range orders {
+		if o.Desc != orders[0].Desc {
+			sameDir = false
+		}
+	}
+	if sameDir {
+		var cols, phs []string
+		for _, o := range orders {
+			cols = append(cols, "["+o.Column+"]")
+			phs = append(phs, "?")
+		}
+		op := " > "
+		if orders[0].Desc {
+			op = " < "
+		}
+		return Expr("("+strings.Join(cols, ", ")+")"+op+"("+strings.Join(phs, ", ")+")", values...)
+	}
+
+	// (a > ?) OR (a = ? AND b < ?) OR ...
+	var alts []string
+	var args []interface{}
+	for i, o := range orders {
+		var conds []string
+		for j := 0; j < i; j++ {
+			conds = append(conds, "["+orders[j].Column+"] = ?")
+			args = append(args, values[j])
+		}
+		conds = append(conds, cmp(o))
+		args = append(args, values[i])
+		alts = append(alts, "("+strings.Join(conds, " AND ")+")")
+	}
+	return Expr(strings.Join(alts, " OR "), args...)
+}
+
+// NextCursor returns the cursor of the page following the last row, which
+// is a struct (or a pointer to it) with fields for the ordered columns, or
+// a value if there is only one ordering. See SeekCursor.
+func NextCursor(orders []Ordering, last interface{}) (string, error) {
+	v := reflect.Indirect(reflect.ValueOf(last))
+	if _, ok := last.(driver.Valuer); ok || v.Kind() != reflect.Struct || v.Type() == reflect.TypeOf(time.Time{}) {
+		if len(orders) != 1 {
+			return "", errors.New("last row must be a struct for multiple orderings")
+		}
+		return Cursor(last)
+	}
+	cols := make([]string, len(orders))
+	for i, o := range orders {
+		// the column without the table name
+		cols[i] = o.Column[strings.LastIndex(o.Column, ".")+1:]
+	}
+	values, err := valuesFor(v.Type(), v, cols)
+	if err != nil {
+		return "", err
+	}
+	return Cursor(values...)
+}
+
+// cursorValue is a value of a cursor preserving its type.
+type cursorValue struct {
+	Int    *int64     `json:"i,omitempty"`
+	Uint   *uint64    `json:"u,omitempty"`
+	Float  *float64   `json:"f,omitempty"`
+	String *string    `json:"s,omitempty"`
+	Bool   *bool      `json:"b,omitempty"`
+	Time   *time.Time `json:"t,omitempty"`
+}
+
+// Cursor returns an opaque cursor encoding the values of the ordered
+// columns of the last row of a page. Pointers are dereferenced. It returns
+// ErrNullCursorValue for a nil value, as the columns must not be NULL.
+// See SeekCursor.
+func Cursor(values ...interface{}) (string, error) {
+	cvs := make([]cursorValue, len(values))
+	for i, v := range values {
+		v, err := derefValue(v)
+		if err != nil {
+			return "", err
+		}
+		if v == nil {
+			return "", ErrNullCursorValue
+		}
+		if t, ok := v.(time.Time); ok {
+			cvs[i].Time = &t
+			continue
+		}
+		if b, ok := v.([]byte); ok {
+			s := string(b)
+			cvs[i].String = &s
+			continue
+		}
+		rv := reflect.ValueOf(v)
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n := rv.Int()
+			cvs[i].Int = &n
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			n := rv.Uint()
+			cvs[i].Uint = &n
+		case reflect.Float32, reflect.Float64:
+			f := rv.Float()
+			cvs[i].Float = &f
+		case reflect.String:
+			s := rv.String()
+			cvs[i].String = &s
+		case reflect.Bool:
+			b := rv.Bool()
+			cvs[i].Bool = &b
+		default:
+			return "", ErrInvalidValue
+		}
+	}
+	data, err := json.Marshal(cvs)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// derefValue returns the value v points to, or the value of a driver.Valuer.
+// A nil pointer results in nil.
+func derefValue(v interface{}) (interface{}, error) {
+	for {
+		rv := reflect.ValueOf(v)
+		if rv.Kind() == reflect.Ptr && rv.IsNil() {
+			return nil, nil
+		}
+		if valuer, ok := v.(driver.Valuer); ok {
+			return valuer.Value()
+		}
+		if rv.Kind() != reflect.Ptr {
+			return v, nil
+		}
+		v = rv.Elem().Interface()
+	}
+}
+
+func decodeCursor(cursor string) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cvs []cursorValue
+	if err := json.Unmarshal(data, &cvs); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	values := make([]interface{}, len(cvs))
+	for i, cv := range cvs {
+		switch {
+		case cv.Int != nil:
+			values[i] = *cv.Int
+		case cv.Uint != nil:
+			values[i] = *cv.Uint
+		case cv.Float != nil:
+			values[i] = *cv.Float
+		case cv.String != nil:
+			values[i] = *cv.String
+		case cv.Bool != nil:
+			values[i] = *cv.Bool
+		case cv.Time != nil:
+			values[i] = *cv.Time
+		}
+	}
+	return values, nil
+}
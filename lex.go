@@ -0,0 +1,190 @@
+package ql
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokOther       tokenKind = iota // operators and punctuation
+	tokSpace                        // white space
+	tokComment                      // -- comment, # comment, or /* comment */
+	tokWord                         // keywords and bare identifiers
+	tokIdent                        // `quoted`, "quoted" (see Dialect.IdentQuote) or [bracketed] identifiers
+	tokString                       // 'single' or "double" quoted strings
+	tokNumber                       // numeric literals
+	tokPlaceholder                  // ?
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// isDashComment reports whether s starts with a -- comment. As in MySQL,
+// the -- must be followed by white space or a control character; e.g.
+// x = --1 is a double negation.
+func isDashComment(s string) bool {
+	if !strings.HasPrefix(s, "--") {
+		return false
+	}
+	if len(s) == 2 {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(s[2:])
+	return unicode.IsSpace(r) || unicode.IsControl(r)
+}
+
+// lex splits the SQL into tokens. Concatenating the texts of the tokens
+// gives back the original SQL. It returns ErrInvalidSyntax if a quoted string,
+// identifier, or comment is not terminated.
+func lex(sql string) ([]token, error) {
+	var toks []token
+	pos := 0
+	for pos < len(sql) {
+		r, w := utf8.DecodeRuneInString(sql[pos:])
+		kind, end := tokOther, pos+w
+
+		switch {
+		case r == '?':
+			kind = tokPlaceholder
+		case unicode.IsSpace(r):
+			kind = tokSpace
+			for end < len(sql) {
+				r, w := utf8.DecodeRuneInString(sql[end:])
+				if !unicode.IsSpace(r) {
+					break
+				}
+				end += w
+			}
+		case r == '#' || isDashComment(sql[pos:]):
+			kind = tokComment
+			if p := strings.IndexByte(sql[end:], '\n'); p != -1 {
+				end += p
+			} else {
+				end = len(sql)
+			}
+		case strings.HasPrefix(sql[pos:], "/*"):
+			kind = tokComment
+			p := strings.Index(sql[pos+2:], "*/")
+			if p == -1 {
+				return nil, ErrInvalidSyntax
+			}
+			end = pos + 2 + p + 2
+		case r == '\'' || r == '"' && D.IdentQuote() != '"':
+			kind = tokString
+			delims := string(r)
+			if D.BackslashEscapes() {
+				delims += `\`
+			}
+			for {
+				p := strings.IndexAny(sql[end:], delims)
+				if p == -1 {
+					return nil, ErrInvalidSyntax
+				}
+				end += p + 1
+				if sql[end-1] == '\\' {
+					end++ // skip the escaped character
+					continue
+				}
+				if end < len(sql) && rune(sql[end]) == r {
+					end++ // doubled quote
+					continue
+				}
+				break
+			}
+		case r == '`' || r == '[' || r == '"':
+			kind = tokIdent
+			closing := r
+			if r == '[' {
+				closing = ']'
+			}
+			for {
+				p := strings.IndexRune(sql[end:], closing)
+				if p == -1 {
+					return nil, ErrInvalidSyntax
+				}
+				end += p + 1
+				if r != '[' && end < len(sql) && rune(sql[end]) == r {
+					end++ // doubled quote
+					continue
+				}
+				break
+			}
+		case unicode.IsDigit(r) || r == '.' && pos+1 < len(sql) && '0' <= sql[pos+1] && sql[pos+1] <= '9':
+			kind = tokNumber
+			for end < len(sql) {
+				c := sql[end]
+				if (c == '+' || c == '-') && (sql[end-1] == 'e' || sql[end-1] == 'E') {
+					end++
+					continue
+				}
+				if c != '.' && !isWordRune(rune(c)) {
+					break
+				}
+				end++
+			}
+		case isWordRune(r):
+			kind = tokWord
+			for end < len(sql) {
+				r, w := utf8.DecodeRuneInString(sql[end:])
+				if !isWordRune(r) {
+					break
+				}
+				end += w
+			}
+		}
+
+		toks = append(toks, token{kind, sql[pos:end]})
+		pos = end
+	}
+	return toks, nil
+}
+
+// checkLiterals returns a *LiteralError if the SQL contains a string or
+// a numeric literal, including in comments. It returns ErrExecutableComment
+// if the SQL contains a comment executed by MySQL (/*! ... */) or an
+// optimizer hint (/*+ ... */).
+func checkLiterals(sql string) error {
+	toks, err := lex(sql)
+	if err != nil {
+		return err
+	}
+	for _, t := range toks {
+		switch t.kind {
+		case tokString, tokNumber:
+			return &LiteralError{Sql: sql, Literal: t.text}
+		case tokComment:
+			if strings.HasPrefix(t.text, "/*!") || strings.HasPrefix(t.text, "/*+") {
+				return ErrExecutableComment
+			}
+			switch err := checkLiterals(commentBody(t.text)).(type) {
+			case nil:
+			case *LiteralError:
+				return &LiteralError{Sql: sql, Literal: err.Literal}
+			default:
+				// e.g. an apostrophe; the comment may hide a literal
+				return &LiteralError{Sql: sql, Literal: t.text}
+			}
+		}
+	}
+	return nil
+}
+
+// commentBody returns the text of the comment without its delimiters.
+func commentBody(comment string) string {
+	switch {
+	case strings.HasPrefix(comment, "/*"):
+		return comment[2 : len(comment)-2]
+	case strings.HasPrefix(comment, "--"):
+		return comment[2:]
+	}
+	return comment[1:] // #
+}
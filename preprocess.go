@@ -40,6 +40,15 @@ var typeOfTime = reflect.TypeOf(time.Time{})
 // replace them with. It returns a blank string and error if the number of placeholders
 // does not match the number of arguments.
 func Preprocess(sql string, vals []interface{}) (string, error) {
+	return preprocessSql(sql, vals, false)
+}
+
+// redactedValue replaces values marked by Secret in redacted queries.
+const redactedValue = "'[redacted]'"
+
+// preprocessSql is like Preprocess, but if redact is true, values marked
+// by Secret are replaced by redactedValue.
+func preprocessSql(sql string, vals []interface{}, redact bool) (string, error) {
 	// Get the number of arguments to add to this query
 	if sql == "" {
 		if len(vals) != 0 {
@@ -48,42 +57,32 @@ func Preprocess(sql string, vals []interface{}) (string, error) {
 		return "", nil
 	}
 
+	toks, err := lex(sql)
+	if err != nil {
+		return "", err
+	}
+
 	curVal := 0
 	buf := new(bytes.Buffer)
-
-	pos := 0
-	for pos < len(sql) {
-		r, w := utf8.DecodeRuneInString(sql[pos:])
-		pos += w
-
+	for _, t := range toks {
 		switch {
-		case r == '?':
+		case t.kind == tokPlaceholder:
 			if curVal >= len(vals) {
 				return "", ErrArgumentMismatch
 			}
-			if err := interpolate(buf, vals[curVal]); err != nil {
+			if _, ok := vals[curVal].(secret); ok && redact {
+				buf.WriteString(redactedValue)
+			} else if err := interpolate(buf, vals[curVal]); err != nil {
 				return "", err
 			}
 			curVal++
-		case r == '`', r == '\'', r == '"':
-			p := strings.IndexRune(sql[pos:], r)
-			if p == -1 {
-				return "", ErrInvalidSyntax
-			}
-			if r == '"' {
-				r = '\''
-			}
-			buf.WriteRune(r)
-			buf.WriteString(sql[pos : pos+p])
-			buf.WriteRune(r)
-			pos += p + 1
-		case r == '[':
-			w := strings.IndexRune(sql[pos:], ']')
-			col := sql[pos : pos+w]
-			D.EscapeIdent(buf, col)
-			pos += w + 1 // size of ']'
+		case t.kind == tokIdent && t.text[0] == '[':
+			D.EscapeIdent(buf, t.text[1:len(t.text)-1])
+		case t.kind == tokString && t.text[0] == '"':
+			writeSingleQuoted(buf, t.text[1:len(t.text)-1])
 		default:
-			buf.WriteRune(r)
+			// comments are copied verbatim
+			buf.WriteString(t.text)
 		}
 	}
 
@@ -93,6 +92,28 @@ func Preprocess(sql string, vals []interface{}) (string, error) {
 	return buf.String(), nil
 }
 
+// writeSingleQuoted writes the contents of a double quoted string as a single
+// quoted string: "" is undoubled and ' is doubled. Escape sequences are
+// copied as they are.
+func writeSingleQuoted(buf *bytes.Buffer, s string) {
+	buf.WriteByte('\'')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' && i+1 < len(s):
+			buf.WriteString(s[i : i+2])
+			i++
+		case c == '"':
+			buf.WriteByte('"')
+			i++ // skip the second quote
+		case c == '\'':
+			buf.WriteString("''")
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	buf.WriteByte('\'')
+}
+
 func interpolate(w query.Writer, v interface{}) error {
 	valuer, ok := v.(driver.Valuer)
 	if ok {
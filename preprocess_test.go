@@ -3,6 +3,9 @@ package ql
 import (
 	"database/sql/driver"
 	"testing"
+	"time"
+
+	"github.com/mibk/ql/dialect"
 )
 
 func TestInterpolate(t *testing.T) {
@@ -83,6 +86,10 @@ func TestInterpolate(t *testing.T) {
 			"SELECT * FROM `user` WHERE `name` = '[nick]'", nil},
 		{`SELECT * FROM [user] WHERE [name] = "nick[]"`, noArgs,
 			"SELECT * FROM `user` WHERE `name` = 'nick[]'", nil},
+		{`SELECT "x""y", "it's", "a\"'b"`, noArgs, `SELECT 'x"y', 'it''s', 'a\"''b'`, nil},
+		{"CREATE TABLE t (a int) -- don't\n", noArgs, "CREATE TABLE t (a int) -- don't\n", nil},
+		{"SELECT ? /* [a]? */ # b?", []interface{}{1}, "SELECT 1 /* [a]? */ # b?", nil},
+		{"SELECT 'it''s', 'a\\'b' FROM [t", noArgs, "", ErrInvalidSyntax},
 	}
 
 	for _, test := range tests {
@@ -96,6 +103,36 @@ func TestInterpolate(t *testing.T) {
 	}
 }
 
+func TestInterpolateTime(t *testing.T) {
+	defer func(d Dialect) { D = d }(D)
+
+	prague, err := time.LoadLocation("Europe/Prague")
+	if err != nil {
+		t.Skip(err)
+	}
+	tm := time.Date(2015, 6, 30, 23, 30, 15, 123456789, prague)
+	tests := []struct {
+		d      Dialect
+		expSql string
+	}{
+		{dialect.Mysql{}, "'2015-06-30 21:30:15' '2015-06-30'"},
+		{dialect.Mysql{Precision: 3}, "'2015-06-30 21:30:15.123' '2015-06-30'"},
+		{dialect.Mysql{Location: prague, Precision: 6}, "'2015-06-30 23:30:15.123456' '2015-06-30'"},
+		{dialect.Mysql{Location: time.FixedZone("", 3*3600)}, "'2015-07-01 00:30:15' '2015-07-01'"},
+	}
+
+	for _, test := range tests {
+		D = test.d
+		str, err := Preprocess("? ?", []interface{}{tm, Date(tm)})
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if str != test.expSql {
+			t.Errorf("\ngot: %v\nwant: %v", str, test.expSql)
+		}
+	}
+}
+
 type myString struct {
 	Present bool
 	Val     string
@@ -107,3 +144,56 @@ func (m myString) Value() (driver.Value, error) {
 	}
 	return nil, nil
 }
+
+func TestSafeMode(t *testing.T) {
+	c := createFakeConnection()
+	c.SafeMode = true
+
+	tests := []struct {
+		b       queryBuilder
+		literal string
+	}{
+		{c.Select("a").From("b").Where("[id] = ? AND `x1` = ?", 1, "a").Where(And{"c >": 2}), ""},
+		{c.Select("a").From("b").Where("name = 'bob'"), "'bob'"},
+		{c.Select("a").From("b").Where("id = 1 -- ?"), "1"},
+		{c.Select("a").From("b").Where("id = ? -- 'x'", 1), "'x'"},
+		{c.Select("a").From("b").Where("id = ? #'x'", 1), "'x'"},
+		{c.Select("a").From("b").Where("id = ? /* or 1=1 */", 1), "1"},
+		{c.Select("a").From("b").Where("id = ? -- don't", 1), "-- don't"},
+		{c.Select("a").From("b").Where("id = --1"), "1"},
+		{c.Select("a").From("b").Where("id = ? -- ok", 1), ""},
+		{c.Select("a").From("b").Having("COUNT(*) > 1.5e3"), "1.5e3"},
+		{c.Update("a").Set("b", Expr(`CONCAT(b, "x")`)), `"x"`},
+		{c.DeleteFrom("a").Where("id = 0x1F"), "0x1F"},
+		{c.Query("SELECT * FROM t LIMIT ?", 1), ""},
+		{c.Query("SELECT * FROM t WHERE s = 'it''s'"), "'it''s'"},
+	}
+
+	for _, test := range tests {
+		_, _, err := preprocess(test.b)
+		if test.literal == "" {
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			continue
+		}
+		lerr, ok := err.(*LiteralError)
+		if !ok {
+			t.Errorf("got error %v, want a *LiteralError", err)
+		} else if lerr.Literal != test.literal {
+			t.Errorf("got literal %s, want %s", lerr.Literal, test.literal)
+		}
+	}
+}
+
+func TestSafeModeExecutableComments(t *testing.T) {
+	c := createFakeConnection()
+	c.SafeMode = true
+
+	for _, cond := range []string{"a = ? /*!50000 OR TRUE */", "a = ? /*+ BKA(t) */"} {
+		_, _, err := preprocess(c.Select("a").From("t").Where(cond, 1))
+		if err != ErrExecutableComment {
+			t.Errorf("%s: got error %v, want ErrExecutableComment", cond, err)
+		}
+	}
+}
@@ -1,38 +1,141 @@
 package ql
 
 type queryBuilder interface {
-	ToSql() (string, []interface{})
+	ToSqlErr() (string, []interface{}, error)
 }
 
-func makeSql(b queryBuilder) string {
-	sql, err := Preprocess(b.ToSql())
+// preprocess returns the interpolated SQL of the builder, or the first error
+// that occurred while building it. logSql is the same SQL with values marked
+// by Secret redacted; it is meant to be passed to the EventReceiver.
+func preprocess(b queryBuilder) (sql, logSql string, err error) {
+	rawSql, args, err := b.ToSqlErr()
 	if err != nil {
-		panic(err)
+		return "", "", err
 	}
-	return sql
+	sql, err = Preprocess(rawSql, args)
+	if err != nil {
+		return "", "", err
+	}
+	logSql = sql
+	for _, arg := range args {
+		if _, ok := arg.(secret); ok {
+			logSql, err = preprocessSql(rawSql, args, true)
+			break
+		}
+	}
+	return sql, logSql, err
 }
 
-// String returns a string representing a preprocessed, interpolated, query.
+// makeSql returns the interpolated SQL of the builder transformed by f, or
+// a description of the error if the SQL cannot be built.
+func makeSql(b queryBuilder, f func(string) string) string {
+	sql, _, err := preprocess(b)
+	if err != nil {
+		return "!ql(ERROR=" + err.Error() + ")"
+	}
+	return f(sql)
+}
+
+func noFormat(sql string) string { return sql }
+
+// String returns a string representing a preprocessed, interpolated, query,
+// or the error that occurred while building it.
 func (q *Query) String() string {
-	return makeSql(q)
+	return makeSql(q, noFormat)
 }
 
-// String returns a string representing a preprocessed, interpolated, query.
+// Fingerprint returns the normalised form of the query. See Fingerprint.
+func (q *Query) Fingerprint() string {
+	return makeSql(q, Fingerprint)
+}
+
+// Pretty returns the query like String, but formatted on multiple lines.
+// See Format.
+func (q *Query) Pretty() string {
+	return makeSql(q, Format)
+}
+
+// String returns a string representing a preprocessed, interpolated, query,
+// or the error that occurred while building it.
 func (b *DeleteBuilder) String() string {
-	return makeSql(b)
+	return makeSql(b, noFormat)
+}
+
+// Fingerprint returns the normalised form of the query. See Fingerprint.
+func (b *DeleteBuilder) Fingerprint() string {
+	return makeSql(b, Fingerprint)
+}
+
+// Pretty returns the query like String, but formatted on multiple lines.
+// See Format.
+func (b *DeleteBuilder) Pretty() string {
+	return makeSql(b, Format)
 }
 
-// String returns a string representing a preprocessed, interpolated, query.
+// String returns a string representing a preprocessed, interpolated, query,
+// or the error that occurred while building it.
 func (b *InsertBuilder) String() string {
-	return makeSql(b)
+	return makeSql(b, noFormat)
+}
+
+// Fingerprint returns the normalised form of the query. See Fingerprint.
+func (b *InsertBuilder) Fingerprint() string {
+	return makeSql(b, Fingerprint)
+}
+
+// Pretty returns the query like String, but formatted on multiple lines.
+// See Format.
+func (b *InsertBuilder) Pretty() string {
+	return makeSql(b, Format)
 }
 
-// String returns a string representing a preprocessed, interpolated, query.
+// String returns a string representing a preprocessed, interpolated, query,
+// or the error that occurred while building it.
 func (b *SelectBuilder) String() string {
-	return makeSql(b)
+	return makeSql(b, noFormat)
+}
+
+// Fingerprint returns the normalised form of the query. See Fingerprint.
+func (b *SelectBuilder) Fingerprint() string {
+	return makeSql(b, Fingerprint)
 }
 
-// String returns a string representing a preprocessed, interpolated, query.
+// Pretty returns the query like String, but formatted on multiple lines.
+// See Format.
+func (b *SelectBuilder) Pretty() string {
+	return makeSql(b, Format)
+}
+
+// String returns a string representing a preprocessed, interpolated, query,
+// or the error that occurred while building it.
 func (b *UpdateBuilder) String() string {
-	return makeSql(b)
+	return makeSql(b, noFormat)
+}
+
+// Fingerprint returns the normalised form of the query. See Fingerprint.
+func (b *UpdateBuilder) Fingerprint() string {
+	return makeSql(b, Fingerprint)
+}
+
+// Pretty returns the query like String, but formatted on multiple lines.
+// See Format.
+func (b *UpdateBuilder) Pretty() string {
+	return makeSql(b, Format)
+}
+
+// String returns a string representing a preprocessed, interpolated, query,
+// or the error that occurred while building it.
+func (b *CompoundBuilder) String() string {
+	return makeSql(b, noFormat)
+}
+
+// Fingerprint returns the normalised form of the query. See Fingerprint.
+func (b *CompoundBuilder) Fingerprint() string {
+	return makeSql(b, Fingerprint)
+}
+
+// Pretty returns the query like String, but formatted on multiple lines.
+// See Format.
+func (b *CompoundBuilder) Pretty() string {
+	return makeSql(b, Format)
 }
@@ -13,6 +13,11 @@ import (
 type Connection struct {
 	DB *sql.DB
 	EventReceiver
+
+	// SafeMode makes the queries fail if SQL passed to Query, Where, Having,
+	// or Expr contains a string or a numeric literal. All values must then be
+	// passed as arguments, which guards against SQL built by fmt.Sprintf.
+	SafeMode bool
 }
 
 // NewConnection instantiates a Connection for a given database/sql connection
@@ -73,14 +78,50 @@ type runner interface {
 	Query(query string, args ...interface{}) (*sql.Rows, error)
 }
 
+// D is the dialect used to build and interpolate queries. Use e.g.
+//
+//	ql.D = dialect.Mysql{Location: loc, Precision: 6}
+//
+// to write times in a different time zone or with fractional seconds.
 var D Dialect = dialect.Mysql{}
 
 // Dialect is an interface that wraps the diverse properties of individual
 // SQL drivers.
 type Dialect interface {
+	// IdentQuote returns the character EscapeIdent quotes identifiers with.
+	// If it is ", double quoted text is an identifier rather than a string.
+	IdentQuote() rune
+
+	// BackslashEscapes reports whether a backslash escapes the following
+	// character in string literals.
+	BackslashEscapes() bool
+
 	EscapeIdent(w query.Writer, ident string)
 	EscapeBool(w query.Writer, b bool)
 	EscapeString(w query.Writer, s string)
 	EscapeTime(w query.Writer, t time.Time)
+	TimeLocation() *time.Location
 	ApplyLimitAndOffset(w query.Writer, limit, offset uint64)
+
+	// ApplyLock writes the row locking clause of a SELECT statement. It
+	// returns an error if the lock is not supported.
+	ApplyLock(w query.Writer, lock query.Lock) error
+
+	// ApplyInsert writes the beginning of an INSERT statement in the mode
+	// up to the table name. It returns an error if the mode is not supported.
+	ApplyInsert(w query.Writer, mode query.InsertMode) error
+
+	// ApplyUpsert writes the upsert clause of an INSERT. Unless u.DoNothing,
+	// the clause is followed by the assignments of the updated columns.
+	// It returns an error if the upsert cannot be expressed in the dialect.
+	ApplyUpsert(w query.Writer, u query.Upsert) error
+
+	// ApplyReturning writes the RETURNING clause of an INSERT, UPDATE, or
+	// DELETE statement (stmt). It returns an error if the clause is not
+	// supported for the statement.
+	ApplyReturning(w query.Writer, stmt query.Statement, cols []string) error
+
+	// EscapeInserted writes a reference to the value of the column being
+	// inserted, which can be used in the assignments of the upsert clause.
+	EscapeInserted(w query.Writer, col string)
 }
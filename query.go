@@ -7,15 +7,19 @@ type Query struct {
 
 	rawSql string
 	args   []interface{}
+	err    error
 }
 
-func newQuery(e EventReceiver, r runner, sql string, args ...interface{}) *Query {
+func newQuery(c *Connection, r runner, sql string, args ...interface{}) *Query {
 	q := &Query{
-		loader:   loader{EventReceiver: e, runner: r},
-		executor: executor{EventReceiver: e, runner: r},
+		loader:   loader{EventReceiver: c, runner: r},
+		executor: executor{EventReceiver: c, runner: r},
 		rawSql:   sql,
 		args:     args,
 	}
+	if c.SafeMode {
+		q.err = checkLiterals(sql)
+	}
 	q.loader.builder = q
 	q.executor.builder = q
 	return q
@@ -31,7 +35,28 @@ func (tx *Tx) Query(sql string, args ...interface{}) *Query {
 	return newQuery(tx.Connection, tx.Tx, sql, args...)
 }
 
-// ToSql returns the raw SQL query and args.
+// Clone returns a copy of the query. Subqueries are shared.
+func (q *Query) Clone() *Query {
+	c := *q
+	c.args = cloneValues(q.args)
+	c.loader.builder = &c
+	c.executor.builder = &c
+	return &c
+}
+
+// ToSql returns the raw SQL query and args. Subqueries among args are
+// expanded. It returns an empty string if the query cannot be built; see
+// ToSqlErr.
 func (q *Query) ToSql() (string, []interface{}) {
-	return q.rawSql, q.args
+	sql, args, _ := q.ToSqlErr()
+	return sql, args
+}
+
+// ToSqlErr is like ToSql, but it also returns the error that occurred while
+// building the query.
+func (q *Query) ToSqlErr() (string, []interface{}, error) {
+	if q.err != nil {
+		return "", nil, q.err
+	}
+	return expandSubqueries(q.rawSql, q.args)
 }
@@ -8,3 +8,60 @@ type Writer interface {
 	WriteString(s string) (n int, err error)
 	WriteRune(r rune) (n int, err error)
 }
+
+// LockMode is the strength of a row locking clause.
+type LockMode int
+
+// Lock modes of a SELECT statement.
+const (
+	NoLock          LockMode = iota
+	ForUpdate                // FOR UPDATE
+	ForShare                 // FOR SHARE
+	LockInShareMode          // LOCK IN SHARE MODE; the older form of FOR SHARE
+)
+
+// LockWait determines what happens if a row to be locked is already locked.
+type LockWait int
+
+// Lock wait options.
+const (
+	Wait       LockWait = iota // wait for the lock
+	NoWait                     // fail immediately
+	SkipLocked                 // skip the locked rows
+)
+
+// Lock describes a row locking clause of a SELECT statement.
+type Lock struct {
+	Mode   LockMode
+	Tables []string // lock only the rows of these tables (OF)
+	Wait   LockWait
+}
+
+// InsertMode is the kind of an INSERT statement.
+type InsertMode int
+
+// Insert modes.
+const (
+	Insert       InsertMode = iota // INSERT INTO
+	InsertIgnore                   // INSERT IGNORE INTO; skips the conflicting rows
+	Replace                        // REPLACE INTO; deletes the conflicting rows first
+)
+
+// Statement is the kind of a data-modifying statement.
+type Statement int
+
+// Statements which can have a RETURNING clause.
+const (
+	InsertStmt Statement = iota // INSERT or REPLACE
+	UpdateStmt                  // UPDATE
+	DeleteStmt                  // DELETE
+)
+
+// Upsert describes how an INSERT statement handles rows conflicting with
+// the existing ones.
+type Upsert struct {
+	Target    []string // columns of the unique index (the ON CONFLICT target)
+	DoNothing bool     // keep the existing rows
+	Columns   []string // the inserted columns
+	Ignore    bool     // the statement is in the InsertIgnore mode
+}
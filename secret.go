@@ -0,0 +1,21 @@
+package ql
+
+import "database/sql/driver"
+
+type secret struct {
+	v interface{}
+}
+
+// Secret marks a value as sensitive. The value is used in the query as usual,
+// but it is redacted in the SQL passed to the EventReceiver. Fields of records
+// can be marked the same way using the struct tag `db:",sensitive"`.
+func Secret(v interface{}) secret {
+	return secret{v}
+}
+
+func (s secret) Value() (driver.Value, error) {
+	if valuer, ok := s.v.(driver.Valuer); ok {
+		return valuer.Value()
+	}
+	return s.v, nil
+}
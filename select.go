@@ -1,6 +1,12 @@
 package ql
 
-import "bytes"
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/mibk/ql/query"
+)
 
 // SelectBuilder contains the clauses for a SELECT statement.
 type SelectBuilder struct {
@@ -9,32 +15,73 @@ type SelectBuilder struct {
 
 	IsDistinct      bool
 	Columns         []string
+	ColumnArgs      []interface{} // args of the placeholders in Columns
 	FromTable       string
+	FromSubquery    *SelectBuilder
+	FromAlias       string
 	GroupBys        []string
 	HavingFragments []*whereFragment
+	Windows         []*namedWindow
+	Lock            query.Lock
 	*baseBuilder
 }
 
-func newSelectBuilder(e EventReceiver, r runner, cols ...string) *SelectBuilder {
+func newSelectBuilder(c *Connection, r runner, cols ...interface{}) *SelectBuilder {
 	b := &SelectBuilder{
-		loader:      loader{EventReceiver: e, runner: r},
-		Columns:     cols,
-		baseBuilder: new(baseBuilder),
+		loader:      loader{EventReceiver: c, runner: r},
+		baseBuilder: newBaseBuilder(c),
 	}
 	b.loader.builder = b
+	for _, col := range cols {
+		switch col := col.(type) {
+		case string:
+			b.Columns = append(b.Columns, col)
+		case expression:
+			e := b.expr(col)
+			b.Columns = append(b.Columns, e.Sql)
+			b.ColumnArgs = append(b.ColumnArgs, e.Values...)
+		case *aliasedExpr:
+			e := b.expr(col.e)
+			b.Columns = append(b.Columns, e.Sql+" AS ["+col.alias+"]")
+			b.ColumnArgs = append(b.ColumnArgs, e.Values...)
+		default:
+			b.fail(fmt.Errorf("invalid column of type %T, only a string, an Expr, or a CASE expression is allowed", col))
+		}
+	}
 	return b
 }
 
-// Select creates a new SelectBuilder that select that given columns.
-func (db *Connection) Select(cols ...string) *SelectBuilder {
+// Select creates a new SelectBuilder that select that given columns. A column
+// is a string, an Expr, or a CASE expression, which can be aliased using As.
+// The args of the columns precede the args of the WHERE clause.
+//
+// Note that a []string of columns has to be converted to []interface{}
+// to be passed as cols... (before Expr columns, cols was ...string).
+func (db *Connection) Select(cols ...interface{}) *SelectBuilder {
 	return newSelectBuilder(db, db.DB, cols...)
 }
 
 // Select creates a new SelectBuilder that select that given columns bound to the transaction.
-func (tx *Tx) Select(cols ...string) *SelectBuilder {
+// See Connection.Select.
+func (tx *Tx) Select(cols ...interface{}) *SelectBuilder {
 	return newSelectBuilder(tx.Connection, tx.Tx, cols...)
 }
 
+// With adds a common table expression named name (which may include a list
+// of columns, e.g. "t(a, b)") to the WITH clause of the statement.
+func (b *SelectBuilder) With(name string, sub *SelectBuilder) *SelectBuilder {
+	b.with(name, sub, false)
+	return b
+}
+
+// WithRecursive adds a recursive common table expression to the WITH clause
+// of the statement. The sub is usually a UNION ALL of the initial SELECT and
+// the SELECT referencing name. See With.
+func (b *SelectBuilder) WithRecursive(name string, sub *CompoundBuilder) *SelectBuilder {
+	b.with(name, sub, true)
+	return b
+}
+
 // Distinct marks the statement as a DISTINCT SELECT.
 func (b *SelectBuilder) Distinct() *SelectBuilder {
 	b.IsDistinct = true
@@ -44,6 +91,41 @@ func (b *SelectBuilder) Distinct() *SelectBuilder {
 // From sets the table to SELECT FROM.
 func (b *SelectBuilder) From(from string) *SelectBuilder {
 	b.FromTable = from
+	b.FromSubquery = nil
+	return b
+}
+
+// FromSelect sets the subquery (aliased as alias) to SELECT FROM.
+func (b *SelectBuilder) FromSelect(sub *SelectBuilder, alias string) *SelectBuilder {
+	b.FromTable = ""
+	b.FromSubquery = sub
+	b.FromAlias = alias
+	return b
+}
+
+// Join appends a JOIN of the table (aliased as alias if not empty) to the
+// statement. The ON condition is a string with args or an And map, as in Where.
+func (b *SelectBuilder) Join(table, alias string, onExprOrMap interface{}, args ...interface{}) *SelectBuilder {
+	b.join("JOIN", table, alias, onExprOrMap, args)
+	return b
+}
+
+// LeftJoin appends a LEFT JOIN to the statement. See Join.
+func (b *SelectBuilder) LeftJoin(table, alias string, onExprOrMap interface{}, args ...interface{}) *SelectBuilder {
+	b.join("LEFT JOIN", table, alias, onExprOrMap, args)
+	return b
+}
+
+// RightJoin appends a RIGHT JOIN to the statement. See Join.
+func (b *SelectBuilder) RightJoin(table, alias string, onExprOrMap interface{}, args ...interface{}) *SelectBuilder {
+	b.join("RIGHT JOIN", table, alias, onExprOrMap, args)
+	return b
+}
+
+// CrossJoin appends a CROSS JOIN of the table (aliased as alias if not empty)
+// to the statement.
+func (b *SelectBuilder) CrossJoin(table, alias string) *SelectBuilder {
+	b.join("CROSS JOIN", table, alias, nil, nil)
 	return b
 }
 
@@ -62,22 +144,37 @@ func (b *SelectBuilder) GroupBy(group string) *SelectBuilder {
 
 // Having appends a HAVING clause to the statement.
 func (b *SelectBuilder) Having(exprOrMap interface{}, args ...interface{}) *SelectBuilder {
-	handleExprType(exprOrMap, args, func(expr string, args ...interface{}) {
-		expr, args = handleShortNotation(expr, args)
-		b.HavingFragments = append(b.HavingFragments, &whereFragment{expr, args})
-	})
+	b.HavingFragments = append(b.HavingFragments, b.conditions(exprOrMap, args)...)
+	return b
+}
+
+// Over appends the column computed by the window function fn (e.g.
+// "ROW_NUMBER()") over the window w. The column is named alias if not empty.
+func (b *SelectBuilder) Over(fn string, w *WindowSpec, alias string) *SelectBuilder {
+	col := fn + " OVER " + w.sql()
+	if alias != "" {
+		col += " AS [" + alias + "]"
+	}
+	b.Columns = append(b.Columns, col)
 	return b
 }
 
-// OrderBy appends a column to ORDER the statement by.
-func (b *SelectBuilder) OrderBy(expr string) *SelectBuilder {
+// Window appends the window w named name to the WINDOW clause of the statement.
+func (b *SelectBuilder) Window(name string, w *WindowSpec) *SelectBuilder {
+	b.Windows = append(b.Windows, &namedWindow{Name: name, Spec: w})
+	return b
+}
+
+// OrderBy appends a column, or an Expr or a CASE expression, to ORDER the
+// statement by.
+func (b *SelectBuilder) OrderBy(expr interface{}) *SelectBuilder {
 	b.orderBy(expr)
 	return b
 }
 
-// Order accepts By map of columns and directions to ORDER the statement by.
-func (b *SelectBuilder) Order(by By) *SelectBuilder {
-	b.order(by)
+// Order appends the orderings (Asc, Desc, or a By map) to ORDER the statement by.
+func (b *SelectBuilder) Order(orders ...Orderer) *SelectBuilder {
+	b.order(orders)
 	return b
 }
 
@@ -93,36 +190,120 @@ func (b *SelectBuilder) Offset(offset uint64) *SelectBuilder {
 	return b
 }
 
+// ForUpdate locks the selected rows for update.
+func (b *SelectBuilder) ForUpdate() *SelectBuilder {
+	b.Lock.Mode = query.ForUpdate
+	return b
+}
+
+// ForShare locks the selected rows in the shared mode.
+func (b *SelectBuilder) ForShare() *SelectBuilder {
+	b.Lock.Mode = query.ForShare
+	return b
+}
+
+// LockInShareMode locks the selected rows in the shared mode using the older
+// syntax, which cannot be combined with Of, NoWait, or SkipLocked.
+func (b *SelectBuilder) LockInShareMode() *SelectBuilder {
+	b.Lock.Mode = query.LockInShareMode
+	return b
+}
+
+// Of restricts the row lock to the rows of the tables.
+func (b *SelectBuilder) Of(tables ...string) *SelectBuilder {
+	b.Lock.Tables = append(b.Lock.Tables, tables...)
+	return b
+}
+
+// NoWait makes the statement fail if a row to be locked is already locked.
+func (b *SelectBuilder) NoWait() *SelectBuilder {
+	b.Lock.Wait = query.NoWait
+	return b
+}
+
+// SkipLocked makes the statement skip the rows which are already locked.
+func (b *SelectBuilder) SkipLocked() *SelectBuilder {
+	b.Lock.Wait = query.SkipLocked
+	return b
+}
+
+// Clone returns a deep copy of the builder, which can be modified without
+// affecting b. Subqueries are shared.
+func (b *SelectBuilder) Clone() *SelectBuilder {
+	c := *b
+	c.Columns = cloneStrings(b.Columns)
+	c.ColumnArgs = cloneValues(b.ColumnArgs)
+	c.GroupBys = cloneStrings(b.GroupBys)
+	c.HavingFragments = cloneFragments(b.HavingFragments)
+	c.Windows = nil
+	for _, w := range b.Windows {
+		c.Windows = append(c.Windows, &namedWindow{Name: w.Name, Spec: w.Spec.clone()})
+	}
+	c.Lock.Tables = cloneStrings(b.Lock.Tables)
+	c.baseBuilder = b.baseBuilder.clone()
+	c.loader.builder = &c
+	return &c
+}
+
 // ToSql serialized the SelectBuilder to a SQL string. It returns the string with
-// placeholders and a slice of query arguments.
+// placeholders and a slice of query arguments. It returns an empty string if
+// the statement cannot be built; see ToSqlErr.
 func (b *SelectBuilder) ToSql() (string, []interface{}) {
+	sql, args, _ := b.ToSqlErr()
+	return sql, args
+}
+
+// ToSqlErr is like ToSql, but it also returns the first error that occurred
+// while building the statement.
+func (b *SelectBuilder) ToSqlErr() (string, []interface{}, error) {
+	if b.err != nil {
+		return "", nil, b.err
+	}
 	if len(b.Columns) == 0 {
-		panic("no columns specified")
+		return "", nil, ErrNoColumns
 	}
-	if len(b.FromTable) == 0 {
-		panic("no table specified")
+	if len(b.FromTable) == 0 && b.FromSubquery == nil {
+		return "", nil, ErrNoTable
 	}
 
 	sql := new(bytes.Buffer)
 	var args []interface{}
 
+	if err := b.buildWith(sql, &args); err != nil {
+		return "", nil, err
+	}
 	sql.WriteString("SELECT ")
 
 	if b.IsDistinct {
 		sql.WriteString("DISTINCT ")
 	}
 
-	for i, s := range b.Columns {
-		if i > 0 {
-			sql.WriteString(", ")
-		}
-		sql.WriteString(s)
+	cols, colArgs, err := expandSubqueries(strings.Join(b.Columns, ", "), b.ColumnArgs)
+	if err != nil {
+		return "", nil, err
 	}
+	sql.WriteString(cols)
+	args = append(args, colArgs...)
 
 	sql.WriteString(" FROM ")
-	sql.WriteString(b.FromTable)
+	if b.FromSubquery != nil {
+		subSql, subArgs, err := b.FromSubquery.ToSqlErr()
+		if err != nil {
+			return "", nil, err
+		}
+		sql.WriteString("(" + subSql + ") ")
+		D.EscapeIdent(sql, b.FromAlias)
+		args = append(args, subArgs...)
+	} else {
+		sql.WriteString(b.FromTable)
+	}
+	if err := b.buildJoins(sql, &args); err != nil {
+		return "", nil, err
+	}
 
-	b.buildWhere(sql, &args)
+	if err := b.buildWhere(sql, &args); err != nil {
+		return "", nil, err
+	}
 
 	if len(b.GroupBys) > 0 {
 		sql.WriteString(" GROUP BY ")
@@ -136,19 +317,40 @@ func (b *SelectBuilder) ToSql() (string, []interface{}) {
 
 	if len(b.HavingFragments) > 0 {
 		sql.WriteString(" HAVING ")
-		writeWhereFragmentsToSql(sql, b.HavingFragments, &args)
+		if err := writeWhereFragmentsToSql(sql, b.HavingFragments, &args); err != nil {
+			return "", nil, err
+		}
 	}
 
-	b.buildOrder(sql)
+	if len(b.Windows) > 0 {
+		sql.WriteString(" WINDOW ")
+		for i, w := range b.Windows {
+			if i > 0 {
+				sql.WriteString(", ")
+			}
+			spec := w.Spec.sql()
+			if spec[0] != '(' {
+				spec = "(" + spec + ")"
+			}
+			sql.WriteString("[" + w.Name + "] AS " + spec)
+		}
+	}
+
+	if err := b.buildOrder(sql, &args); err != nil {
+		return "", nil, err
+	}
 	b.buildLimitAndOffset(sql)
+	if err := D.ApplyLock(sql, b.Lock); err != nil {
+		return "", nil, err
+	}
 
-	return sql.String(), args
+	return sql.String(), args, nil
 }
 
 // One executes the query and loads the resulting data into the dest, which can be either
 // a struct, or a primitive value. Returns ErrNotFound if no item was found, and it was
-// therefore not set.
+// therefore not set. The query is executed with LIMIT 1, which is not kept
+// in the builder.
 func (b *SelectBuilder) One(dest interface{}) error {
-	b.Limit(1)
-	return b.loader.One(dest)
+	return b.Clone().Limit(1).loader.One(dest)
 }
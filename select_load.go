@@ -16,6 +16,15 @@ type loader struct {
 	EventReceiver
 	runner
 	builder queryBuilder
+	events  string // prefix of the event names; dbr.select if empty
+}
+
+// event returns the name of the event with the suffix, e.g. dbr.select.load_all.query.
+func (l loader) event(suffix string) string {
+	if l.events == "" {
+		return "dbr.select" + suffix
+	}
+	return l.events + suffix
 }
 
 // All executes the query and loads the resulting data into the dest, which can be a slice of
@@ -75,30 +84,32 @@ func (l loader) One(dest interface{}) error {
 // dest must be a pointer to a slice of pointers to structs. It returns the number of items
 // found (which is not necessarily the number of items set).
 func (l loader) loadStructs(dest interface{}, valueOfDest reflect.Value, elemType reflect.Type) (int, error) {
-	fullSql, err := Preprocess(l.builder.ToSql())
+	fullSql, logSql, err := preprocess(l.builder)
 	if err != nil {
-		return 0, l.EventErr("dbr.select.load_all.interpolate", err)
+		return 0, l.EventErr(l.event(".load_all.interpolate"), err)
 	}
 
 	numberOfRowsReturned := 0
 
 	startTime := time.Now()
-	defer func() { l.TimingKv("dbr.select", time.Since(startTime).Nanoseconds(), kvs{"sql": fullSql}) }()
+	defer func() {
+		l.TimingKv(l.event(""), time.Since(startTime).Nanoseconds(), kvs{"sql": logSql, "fingerprint": Fingerprint(logSql)})
+	}()
 
 	rows, err := l.runner.Query(fullSql)
 	if err != nil {
-		return 0, l.EventErrKv("dbr.select.load_all.query", err, kvs{"sql": fullSql})
+		return 0, l.EventErrKv(l.event(".load_all.query"), err, kvs{"sql": logSql})
 	}
 	defer rows.Close()
 
 	columns, err := rows.Columns()
 	if err != nil {
-		return numberOfRowsReturned, l.EventErrKv("dbr.select.load_one.rows.Columns", err, kvs{"sql": fullSql})
+		return numberOfRowsReturned, l.EventErrKv(l.event(".load_one.rows.Columns"), err, kvs{"sql": logSql})
 	}
 
 	fieldMap, err := calculateFieldMap(elemType, columns, false)
 	if err != nil {
-		return numberOfRowsReturned, l.EventErrKv("dbr.select.load_all.calculateFieldMap", err, kvs{"sql": fullSql})
+		return numberOfRowsReturned, l.EventErrKv(l.event(".load_all.calculateFieldMap"), err, kvs{"sql": logSql})
 	}
 
 	// Build a 'holder', which is an []interface{}. Each value will be the set to address of the field corresponding to our newly made records:
@@ -114,13 +125,13 @@ func (l loader) loadStructs(dest interface{}, valueOfDest reflect.Value, elemTyp
 		// Prepare the holder for this record
 		scannable, err := prepareHolderFor(newRecord, fieldMap, holder)
 		if err != nil {
-			return numberOfRowsReturned, l.EventErrKv("dbr.select.load_all.holderFor", err, kvs{"sql": fullSql})
+			return numberOfRowsReturned, l.EventErrKv(l.event(".load_all.holderFor"), err, kvs{"sql": logSql})
 		}
 
 		// Load up our new structure with the row's values
 		err = rows.Scan(scannable...)
 		if err != nil {
-			return numberOfRowsReturned, l.EventErrKv("dbr.select.load_all.scan", err, kvs{"sql": fullSql})
+			return numberOfRowsReturned, l.EventErrKv(l.event(".load_all.scan"), err, kvs{"sql": logSql})
 		}
 
 		// Append our new record to the slice:
@@ -132,7 +143,7 @@ func (l loader) loadStructs(dest interface{}, valueOfDest reflect.Value, elemTyp
 
 	// Check for errors at the end. Supposedly these are error that can happen during iteration.
 	if err = rows.Err(); err != nil {
-		return numberOfRowsReturned, l.EventErrKv("dbr.select.load_all.rows_err", err, kvs{"sql": fullSql})
+		return numberOfRowsReturned, l.EventErrKv(l.event(".load_all.rows_err"), err, kvs{"sql": logSql})
 	}
 
 	return numberOfRowsReturned, nil
@@ -141,30 +152,30 @@ func (l loader) loadStructs(dest interface{}, valueOfDest reflect.Value, elemTyp
 // loadStruct executes the query and loads the resulting data into a struct,
 // dest must be a pointer to a struct. Returns ErrNotFound if nothing was found.
 func (l loader) loadStruct(dest interface{}, valueOfDest reflect.Value) error {
-	fullSql, err := Preprocess(l.builder.ToSql())
+	fullSql, logSql, err := preprocess(l.builder)
 	if err != nil {
 		return err
 	}
 
 	startTime := time.Now()
 	defer func() {
-		l.TimingKv("dbr.select", time.Since(startTime).Nanoseconds(), kvs{"sql": fullSql})
+		l.TimingKv(l.event(""), time.Since(startTime).Nanoseconds(), kvs{"sql": logSql, "fingerprint": Fingerprint(logSql)})
 	}()
 
 	rows, err := l.runner.Query(fullSql)
 	if err != nil {
-		return l.EventErrKv("dbr.select.load_one.query", err, kvs{"sql": fullSql})
+		return l.EventErrKv(l.event(".load_one.query"), err, kvs{"sql": logSql})
 	}
 	defer rows.Close()
 
 	columns, err := rows.Columns()
 	if err != nil {
-		return l.EventErrKv("dbr.select.load_one.rows.Columns", err, kvs{"sql": fullSql})
+		return l.EventErrKv(l.event(".load_one.rows.Columns"), err, kvs{"sql": logSql})
 	}
 
 	fieldMap, err := calculateFieldMap(valueOfDest.Type(), columns, false)
 	if err != nil {
-		return l.EventErrKv("dbr.select.load_one.calculateFieldMap", err, kvs{"sql": fullSql})
+		return l.EventErrKv(l.event(".load_one.calculateFieldMap"), err, kvs{"sql": logSql})
 	}
 
 	// Build a 'holder', which is an []interface{}. Each value will be the set to address of the field corresponding to our newly made records:
@@ -174,19 +185,19 @@ func (l loader) loadStruct(dest interface{}, valueOfDest reflect.Value) error {
 		// Build a 'holder', which is an []interface{}. Each value will be the address of the field corresponding to our newly made record:
 		scannable, err := prepareHolderFor(valueOfDest, fieldMap, holder)
 		if err != nil {
-			return l.EventErrKv("dbr.select.load_one.holderFor", err, kvs{"sql": fullSql})
+			return l.EventErrKv(l.event(".load_one.holderFor"), err, kvs{"sql": logSql})
 		}
 
 		// Load up our new structure with the row's values
 		err = rows.Scan(scannable...)
 		if err != nil {
-			return l.EventErrKv("dbr.select.load_one.scan", err, kvs{"sql": fullSql})
+			return l.EventErrKv(l.event(".load_one.scan"), err, kvs{"sql": logSql})
 		}
 		return nil
 	}
 
 	if err := rows.Err(); err != nil {
-		return l.EventErrKv("dbr.select.load_one.rows_err", err, kvs{"sql": fullSql})
+		return l.EventErrKv(l.event(".load_one.rows_err"), err, kvs{"sql": logSql})
 	}
 
 	return ErrNotFound
@@ -195,7 +206,7 @@ func (l loader) loadStruct(dest interface{}, valueOfDest reflect.Value) error {
 // loadValues executes the query and loads the resulting data into a slice of
 // primitive values. Returns ErrNotFound if no value was found, and it was therefore not set.
 func (l loader) loadValues(dest interface{}, valueOfDest reflect.Value, elemType reflect.Type) (int, error) {
-	fullSql, err := Preprocess(l.builder.ToSql())
+	fullSql, logSql, err := preprocess(l.builder)
 	if err != nil {
 		return 0, err
 	}
@@ -203,11 +214,13 @@ func (l loader) loadValues(dest interface{}, valueOfDest reflect.Value, elemType
 	numberOfRowsReturned := 0
 
 	startTime := time.Now()
-	defer func() { l.TimingKv("dbr.select", time.Since(startTime).Nanoseconds(), kvs{"sql": fullSql}) }()
+	defer func() {
+		l.TimingKv(l.event(""), time.Since(startTime).Nanoseconds(), kvs{"sql": logSql, "fingerprint": Fingerprint(logSql)})
+	}()
 
 	rows, err := l.runner.Query(fullSql)
 	if err != nil {
-		return numberOfRowsReturned, l.EventErrKv("dbr.select.load_all_values.query", err, kvs{"sql": fullSql})
+		return numberOfRowsReturned, l.EventErrKv(l.event(".load_all_values.query"), err, kvs{"sql": logSql})
 	}
 	defer rows.Close()
 
@@ -219,7 +232,7 @@ func (l loader) loadValues(dest interface{}, valueOfDest reflect.Value, elemType
 
 		err = rows.Scan(pointerToNewValue.Interface())
 		if err != nil {
-			return numberOfRowsReturned, l.EventErrKv("dbr.select.load_all_values.scan", err, kvs{"sql": fullSql})
+			return numberOfRowsReturned, l.EventErrKv(l.event(".load_all_values.scan"), err, kvs{"sql": logSql})
 		}
 
 		// Append our new value to the slice:
@@ -230,7 +243,7 @@ func (l loader) loadValues(dest interface{}, valueOfDest reflect.Value, elemType
 	valueOfDest.Set(sliceValue)
 
 	if err := rows.Err(); err != nil {
-		return numberOfRowsReturned, l.EventErrKv("dbr.select.load_all_values.rows_err", err, kvs{"sql": fullSql})
+		return numberOfRowsReturned, l.EventErrKv(l.event(".load_all_values.rows_err"), err, kvs{"sql": logSql})
 	}
 
 	return numberOfRowsReturned, nil
@@ -239,33 +252,33 @@ func (l loader) loadValues(dest interface{}, valueOfDest reflect.Value, elemType
 // loadValue executes the query and loads the resulting data into a primitive value.
 // Returns ErrNotFound if no value was found, and it was therefore not set.
 func (l loader) loadValue(dest interface{}) error {
-	fullSql, err := Preprocess(l.builder.ToSql())
+	fullSql, logSql, err := preprocess(l.builder)
 	if err != nil {
 		return err
 	}
 
 	startTime := time.Now()
 	defer func() {
-		l.TimingKv("dbr.select", time.Since(startTime).Nanoseconds(), kvs{"sql": fullSql})
+		l.TimingKv(l.event(""), time.Since(startTime).Nanoseconds(), kvs{"sql": logSql, "fingerprint": Fingerprint(logSql)})
 	}()
 
 	// Run the query:
 	rows, err := l.runner.Query(fullSql)
 	if err != nil {
-		return l.EventErrKv("dbr.select.load_value.query", err, kvs{"sql": fullSql})
+		return l.EventErrKv(l.event(".load_value.query"), err, kvs{"sql": logSql})
 	}
 	defer rows.Close()
 
 	if rows.Next() {
 		err = rows.Scan(dest)
 		if err != nil {
-			return l.EventErrKv("dbr.select.load_value.scan", err, kvs{"sql": fullSql})
+			return l.EventErrKv(l.event(".load_value.scan"), err, kvs{"sql": logSql})
 		}
 		return nil
 	}
 
 	if err := rows.Err(); err != nil {
-		return l.EventErrKv("dbr.select.load_value.rows_err", err, kvs{"sql": fullSql})
+		return l.EventErrKv(l.event(".load_value.rows_err"), err, kvs{"sql": logSql})
 	}
 
 	return ErrNotFound
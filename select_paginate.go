@@ -0,0 +1,51 @@
+package ql
+
+import "github.com/mibk/ql/query"
+
+// countBuilder returns the query counting the rows returned by b. ORDER BY,
+// LIMIT, OFFSET, and the locking clause are dropped. A DISTINCT or a grouped
+// query is wrapped in a subquery.
+func (b *SelectBuilder) countBuilder() *SelectBuilder {
+	c := b.Clone()
+	c.OrderBys, c.OrderArgs = nil, nil
+	c.LimitValid, c.OffsetValid = false, false
+	c.Lock = query.Lock{}
+	if !c.IsDistinct && len(c.GroupBys) == 0 {
+		c.Columns, c.ColumnArgs = []string{"COUNT(*)"}, nil
+		return c
+	}
+	w := &SelectBuilder{
+		loader:       b.loader,
+		Columns:      []string{"COUNT(*)"},
+		FromSubquery: c,
+		FromAlias:    "t",
+		baseBuilder:  &baseBuilder{buildState: buildState{safe: b.safe}},
+	}
+	w.loader.builder = w
+	return w
+}
+
+// Count executes a query counting the rows the statement would return
+// regardless of its LIMIT and OFFSET. The builder is not modified.
+func (b *SelectBuilder) Count() (int64, error) {
+	return b.countBuilder().ReturnInt64()
+}
+
+// Paginate loads the page-th page (starting at 1) of perPage items into
+// the dest like All, and returns the total number of items as Count does.
+// The statement should be ordered so that the pages are stable. The builder
+// is not modified.
+func (b *SelectBuilder) Paginate(page, perPage uint64, dest interface{}) (total int64, err error) {
+	if page == 0 {
+		page = 1
+	}
+	total, err = b.Count()
+	if err != nil {
+		return 0, err
+	}
+	if uint64(total) <= (page-1)*perPage {
+		return total, nil // there are no items on the page
+	}
+	_, err = b.Clone().Limit(perPage).Offset((page - 1) * perPage).All(dest)
+	return total, err
+}
@@ -2,7 +2,9 @@ package ql
 
 import (
 	"testing"
+	"time"
 
+	"github.com/mibk/ql/dialect"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -144,13 +146,9 @@ func TestSelectWhereMapSql(t *testing.T) {
 	assert.Equal(t, sql, "SELECT a FROM b WHERE ([a] = ?)")
 	assert.Equal(t, args, []interface{}{1})
 
-	sql, args = s.Select("a").From("b").Where(And{"a": 1, "b": true}).ToSql()
-	if sql == "SELECT a FROM b WHERE ([a] = ?) AND ([b] = ?)" {
-		assert.Equal(t, args, []interface{}{1, true})
-	} else {
-		assert.Equal(t, sql, "SELECT a FROM b WHERE ([b] = ?) AND ([a] = ?)")
-		assert.Equal(t, args, []interface{}{true, 1})
-	}
+	sql, args = s.Select("a").From("b").Where(And{"b": true, "a": 1}).ToSql()
+	assert.Equal(t, sql, "SELECT a FROM b WHERE ([a] = ?) AND ([b] = ?)")
+	assert.Equal(t, args, []interface{}{1, true})
 
 	sql, args = s.Select("a").From("b").Where(And{"a": nil}).ToSql()
 	assert.Equal(t, sql, "SELECT a FROM b WHERE ([a] IS NULL)")
@@ -186,12 +184,24 @@ func TestSelectWhereEqSql(t *testing.T) {
 	s := createFakeConnection()
 
 	sql, args := s.Select("a").From("b").Where(And{"a": 1, "b": []int64{1, 2, 3}}).ToSql()
-	if sql == "SELECT a FROM b WHERE ([a] = ?) AND ([b] IN ?)" {
-		assert.Equal(t, args, []interface{}{1, []int64{1, 2, 3}})
-	} else {
-		assert.Equal(t, sql, "SELECT a FROM b WHERE ([b] IN ?) AND ([a] = ?)")
-		assert.Equal(t, args, []interface{}{[]int64{1, 2, 3}, 1})
-	}
+	assert.Equal(t, sql, "SELECT a FROM b WHERE ([a] = ?) AND ([b] IN ?)")
+	assert.Equal(t, args, []interface{}{1, []int64{1, 2, 3}})
+}
+
+func TestSelectDeterministicSql(t *testing.T) {
+	s := createFakeConnection()
+
+	sql, args := s.Select("a").From("b").
+		Where(AndList{"z", 1, "y >", 2}).
+		Where(Or{"d": 4, "c": 3}).
+		Where(OrList{"f", 6, "e", 5}).
+		Order(By{"x": Desc, "w": Asc}, Sort("v", Desc)).
+		Order(Sort("u", Asc)).
+		ToSql()
+	assert.Equal(t, sql, "SELECT a FROM b WHERE ([z] = ?) AND ([y] > ?)"+
+		" AND (([c] = ?) OR ([d] = ?)) AND (([f] = ?) OR ([e] = ?))"+
+		" ORDER BY [w] ASC, [x] DESC, [v] DESC, [u] ASC")
+	assert.Equal(t, args, []interface{}{1, 2, 3, 4, 6, 5})
 }
 
 func TestSelectBySql(t *testing.T) {
@@ -211,6 +221,329 @@ func TestSelectBySql(t *testing.T) {
 	assert.Equal(t, args, []interface{}{9, []int{5, 6, 7}})
 }
 
+func TestSelectJoinToSql(t *testing.T) {
+	s := createFakeConnection()
+
+	sql, args := s.Select("u.name", "COUNT(p.id)").
+		From("user u").
+		Join("post", "p", "p.user_id = u.id AND p.state = ?", "published").
+		LeftJoin("comment", "c", And{"c.post_id": 5}).
+		RightJoin("tag t", "", "t.id = p.tag_id").
+		CrossJoin("settings", "").
+		Where("u.age >", 18).
+		ToSql()
+
+	assert.Equal(t, sql, "SELECT u.name, COUNT(p.id) FROM user u"+
+		" JOIN post `p` ON (p.user_id = u.id AND p.state = ?)"+
+		" LEFT JOIN comment `c` ON ([c.post_id] = ?)"+
+		" RIGHT JOIN tag t ON (t.id = p.tag_id)"+
+		" CROSS JOIN settings"+
+		" WHERE ([u.age] > ?)")
+	assert.Equal(t, args, []interface{}{"published", 5, 18})
+}
+
+func TestSelectSubqueryToSql(t *testing.T) {
+	s := createFakeConnection()
+
+	sub := s.Select("user_id").From("post").Where("[state] = ?", "published")
+	sql, args := s.Select("t.n").
+		FromSelect(s.Select("COUNT(*) AS n").From("[user]").Where("age >", 18), "t").
+		Where("id", sub).
+		Where("score > ?", s.Select("AVG(score)").From("user").Where("team", 3)).
+		Where(Exists(s.Select("1").From("ban").Where("ban.user_id = t.id AND ban.until > ?", 7))).
+		Having(NotExists(sub)).
+		ToSql()
+
+	assert.Equal(t, sql, "SELECT t.n FROM (SELECT COUNT(*) AS n FROM [user] WHERE ([age] > ?)) `t`"+
+		" WHERE ([id] IN (SELECT user_id FROM post WHERE ([state] = ?)))"+
+		" AND ([score] > (SELECT AVG(score) FROM user WHERE ([team] = ?)))"+
+		" AND (EXISTS (SELECT 1 FROM ban WHERE (ban.user_id = t.id AND ban.until > ?)))"+
+		" HAVING (NOT EXISTS (SELECT user_id FROM post WHERE ([state] = ?)))")
+	assert.Equal(t, args, []interface{}{18, "published", 3, 7, "published"})
+
+	sql, args = s.Query("SELECT * FROM [user] WHERE [id] IN ? AND [name] = ?", sub, "x").ToSql()
+	assert.Equal(t, sql, "SELECT * FROM [user] WHERE [id] IN (SELECT user_id FROM post WHERE ([state] = ?)) AND [name] = ?")
+	assert.Equal(t, args, []interface{}{"published", "x"})
+
+	defer func(d Dialect) { D = d }(D)
+	D = dialect.Postgres{}
+
+	str := s.Select("t.n").FromSelect(s.Select("COUNT(*) AS n").From("[user]").Where("age >", 18), "t").String()
+	assert.Equal(t, str, `SELECT t.n FROM (SELECT COUNT(*) AS n FROM "user" WHERE ("age" > 18)) "t"`)
+}
+
+func TestSelectWithToSql(t *testing.T) {
+	s := createFakeConnection()
+
+	tree := s.Compound(s.Select("id", "parent_id").From("category").Where("id", 7)).
+		UnionAll(s.Select("c.id", "c.parent_id").From("category c").Join("tree", "t", "c.parent_id = t.id"))
+	sql, args := s.Select("*").
+		With("active", s.Select("id").From("category").Where("active", true)).
+		WithRecursive("tree(id, parent_id)", tree).
+		From("tree").
+		Where("id IN (SELECT id FROM active) AND id <> ?", 7).
+		ToSql()
+
+	assert.Equal(t, sql, "WITH RECURSIVE active AS (SELECT id FROM category WHERE ([active] = ?)),"+
+		" tree(id, parent_id) AS ((SELECT id, parent_id FROM category WHERE ([id] = ?))"+
+		" UNION ALL (SELECT c.id, c.parent_id FROM category c JOIN tree `t` ON (c.parent_id = t.id)))"+
+		" SELECT * FROM tree WHERE (id IN (SELECT id FROM active) AND id <> ?)")
+	assert.Equal(t, args, []interface{}{true, 7, 7})
+}
+
+func TestSelectWindowToSql(t *testing.T) {
+	s := createFakeConnection()
+
+	sql, _ := s.Select("name").
+		Over("ROW_NUMBER()", Window().PartitionBy("dept_id", "team").OrderBy("salary DESC"), "rank").
+		Over("SUM(salary)", WindowRef("w").Frame("ROWS UNBOUNDED PRECEDING"), "").
+		Over("AVG(salary)", WindowRef("w"), "avg").
+		From("employee").
+		Window("w", Window().PartitionBy("dept_id").Order(By{"hired": Asc})).
+		ToSql()
+
+	assert.Equal(t, sql, "SELECT name,"+
+		" ROW_NUMBER() OVER (PARTITION BY [dept_id], [team] ORDER BY salary DESC) AS [rank],"+
+		" SUM(salary) OVER ([w] ROWS UNBOUNDED PRECEDING),"+
+		" AVG(salary) OVER [w] AS [avg]"+
+		" FROM employee WINDOW [w] AS (PARTITION BY [dept_id] ORDER BY [hired] ASC)")
+}
+
+func TestSelectWhereOrNotSql(t *testing.T) {
+	s := createFakeConnection()
+
+	sql, args := s.Select("a").From("b").Where(Or{"a": nil}).Where(Not("c >", 5)).ToSql()
+	assert.Equal(t, sql, "SELECT a FROM b WHERE ([a] IS NULL) AND (NOT ([c] > ?))")
+	assert.Equal(t, args, []interface{}{5})
+
+	sql, args = s.Select("a").From("b").
+		Where(AnyOf(
+			Expr("x = ? OR y = ?", 1, 2),
+			AllOf(Or{"d": []int{3, 4}}, Not(Or{"e": true}), "f IS NOT NULL"),
+			Or{},
+		)).
+		Having(AllOf()).
+		ToSql()
+	assert.Equal(t, sql, "SELECT a FROM b WHERE ((x = ? OR y = ?)"+
+		" OR (([d] IN ?) AND (NOT ([e] = ?)) AND (f IS NOT NULL))"+
+		" OR (1=0)) HAVING (1=1)")
+	assert.Equal(t, args, []interface{}{1, 2, []int{3, 4}, true})
+
+	sql, args = s.Select("a").From("b").LeftJoin("c", "", AnyOf("c.id = b.c_id", Or{"c.id": nil})).ToSql()
+	assert.Equal(t, sql, "SELECT a FROM b LEFT JOIN c ON ((c.id = b.c_id) OR ([c.id] IS NULL))")
+	assert.Equal(t, len(args), 0)
+}
+
+func TestSelectLockToSql(t *testing.T) {
+	s := createFakeConnection()
+
+	sql, _ := s.Select("id").From("job").Where("state", "new").Limit(1).ForUpdate().SkipLocked().ToSql()
+	assert.Equal(t, sql, "SELECT id FROM job WHERE ([state] = ?) LIMIT 1 FOR UPDATE SKIP LOCKED")
+
+	sql, _ = s.Select("*").From("job j").Join("worker", "w", "w.id = j.worker_id").ForShare().Of("j", "w").NoWait().ToSql()
+	assert.Equal(t, sql, "SELECT * FROM job j JOIN worker `w` ON (w.id = j.worker_id) FOR SHARE OF `j`, `w` NOWAIT")
+
+	sql, _ = s.Select("*").From("job").LockInShareMode().ToSql()
+	assert.Equal(t, sql, "SELECT * FROM job LOCK IN SHARE MODE")
+
+	_, _, err := s.Select("*").From("job").LockInShareMode().NoWait().ToSqlErr()
+	assert.Equal(t, err, &dialect.UnsupportedError{Dialect: "MySQL", Feature: "OF, NOWAIT, or SKIP LOCKED with LOCK IN SHARE MODE"})
+
+	defer func(d Dialect) { D = d }(D)
+	D = dialect.Postgres{}
+
+	str := s.Select("*").From("job j").Join("worker", "w", "w.id = j.worker_id").
+		Where("[j.state] = ?", "new").ForUpdate().Of("j").SkipLocked().String()
+	assert.Equal(t, str, `SELECT * FROM job j JOIN worker "w" ON (w.id = j.worker_id) WHERE ("j"."state" = 'new') FOR UPDATE OF "j" SKIP LOCKED`)
+}
+
+func TestSelectClone(t *testing.T) {
+	s := createFakeConnection()
+
+	b := s.Select("a").From("b").Where("c = ?", 1).Window("w", Window().PartitionBy("d"))
+	b.WhereFragments[0].Values = append(make([]interface{}, 0, 4), 1)
+	c := b.Clone().Where("e = ?", 2).OrderBy("f").Limit(1)
+	c.WhereFragments[0].Values = append(c.WhereFragments[0].Values, 3)
+	c.Windows[0].Spec.PartitionBy("g")
+
+	sql, args := b.ToSql()
+	assert.Equal(t, sql, "SELECT a FROM b WHERE ([c] = ?) WINDOW [w] AS (PARTITION BY [d])")
+	assert.Equal(t, args, []interface{}{1})
+
+	sql, _ = c.ToSql()
+	assert.Equal(t, sql, "SELECT a FROM b WHERE ([c] = ?) AND ([e] = ?) WINDOW [w] AS (PARTITION BY [d], [g]) ORDER BY f LIMIT 1")
+	assert.Equal(t, c.loader.builder, c)
+
+	cb := s.Compound(b).Union(s.Select("a").From("h"))
+	cc := cb.Clone().UnionAll(s.Select("a").From("i")).Limit(5)
+	sql, _ = cb.ToSql()
+	assert.Equal(t, sql, "(SELECT a FROM b WHERE ([c] = ?) WINDOW [w] AS (PARTITION BY [d])) UNION (SELECT a FROM h)")
+	assert.Equal(t, len(cc.Parts), 3)
+}
+
+func TestSelectToSqlErr(t *testing.T) {
+	s := createFakeConnection()
+
+	tests := []struct {
+		b   queryBuilder
+		err string
+	}{
+		{s.Select("a"), "no table specified"},
+		{s.Select().From("b"), "no columns specified"},
+		{s.Select("a").From("b").Where(AndList{"c"}), "odd number of elements in a list of conditions"},
+		{s.Select("a").From("b").Where(5), "invalid argument of type int passed to Where, only a string, an And or an Or map or list, an Expr, or a condition is allowed"},
+		{s.Select("a").From("b").Where(And{"c": 1}, 2), "args are not expected when passing an And map"},
+		{s.Select("a").From("b").Where(""), "empty condition expression"},
+		{s.Select("a").From("b").Where("c IN ?", s.Select("d")), "no table specified"},
+		{s.Select("a").FromSelect(s.Select("d"), "t"), "no table specified"},
+		{s.Compound(s.Select("a").From("b")).Union(s.Select().From("c")), "no columns specified"},
+		{s.Update("a").Set("b", 1).With("t", s.Select("c")), "no table specified"},
+		{s.Update("a"), "no set clauses specified"},
+		{s.DeleteFrom(""), "no table specified"},
+		{s.InsertInto("a").Columns("b"), "no values or records specified"},
+		{s.InsertInto("a").Pair("b", 1).Values(2).Pair("c", 3), "pair only allows you to specify 1 record to insert"},
+		{s.Query("SELECT ?", s.Select("a").Where("b = ?", 1)), "no table specified"},
+	}
+	for _, test := range tests {
+		sql, args, err := test.b.ToSqlErr()
+		if err == nil {
+			t.Errorf("%s: expected error %q", sql, test.err)
+			continue
+		}
+		assert.Equal(t, err.Error(), test.err)
+		assert.Equal(t, sql, "")
+		assert.Nil(t, args)
+	}
+
+	b := s.Select("a")
+	assert.Equal(t, b.String(), "!ql(ERROR=no table specified)")
+	assert.Equal(t, b.Pretty(), "!ql(ERROR=no table specified)")
+	sql, _ := b.ToSql()
+	assert.Equal(t, sql, "")
+}
+
+func TestSelectCountToSql(t *testing.T) {
+	s := createFakeConnection()
+
+	b := s.Select("a", "b").From("c").Where("d = ?", 1).OrderBy("a").Limit(10).Offset(20).ForUpdate()
+	sql, args := b.countBuilder().ToSql()
+	assert.Equal(t, sql, "SELECT COUNT(*) FROM c WHERE ([d] = ?)")
+	assert.Equal(t, args, []interface{}{1})
+
+	sql, _ = b.ToSql()
+	assert.Equal(t, sql, "SELECT a, b FROM c WHERE ([d] = ?) ORDER BY a LIMIT 10 OFFSET 20 FOR UPDATE")
+
+	sql, args = s.Select("a").Distinct().From("c").Where("d = ?", 1).OrderBy("a").countBuilder().ToSql()
+	assert.Equal(t, sql, "SELECT COUNT(*) FROM (SELECT DISTINCT a FROM c WHERE ([d] = ?)) `t`")
+	assert.Equal(t, args, []interface{}{1})
+
+	sql, _ = s.Select("a, COUNT(*)").From("c").GroupBy("a").Having("COUNT(*) > ?", 1).countBuilder().ToSql()
+	assert.Equal(t, sql, "SELECT COUNT(*) FROM (SELECT a, COUNT(*) FROM c GROUP BY a HAVING (COUNT(*) > ?)) `t`")
+}
+
+func TestSelectSeekToSql(t *testing.T) {
+	s := createFakeConnection()
+
+	sql, args := s.Select("a").From("b").Seek([]Ordering{Sort("id", Asc)}, 5).Limit(10).ToSql()
+	assert.Equal(t, sql, "SELECT a FROM b WHERE ([id] > ?) ORDER BY [id] ASC LIMIT 10")
+	assert.Equal(t, args, []interface{}{5})
+
+	sql, args = s.Select("a").From("b").Where("c = ?", 1).Seek([]Ordering{Sort("t", Desc), Sort("id", Desc)}, 7, 5).ToSql()
+	assert.Equal(t, sql, "SELECT a FROM b WHERE ([c] = ?) AND (([t], [id]) < (?, ?)) ORDER BY [t] DESC, [id] DESC")
+	assert.Equal(t, args, []interface{}{1, 7, 5})
+
+	sql, args = s.Select("a").From("b").Seek([]Ordering{Sort("t", Desc), Sort("n", Asc), Sort("id", Asc)}, 7, "x", 5).ToSql()
+	assert.Equal(t, sql, "SELECT a FROM b WHERE (([t] < ?) OR ([t] = ? AND [n] > ?) OR ([t] = ? AND [n] = ? AND [id] > ?))"+
+		" ORDER BY [t] DESC, [n] ASC, [id] ASC")
+	assert.Equal(t, args, []interface{}{7, 7, "x", 7, "x", 5})
+
+	sql, _ = s.Select("a").From("b").Seek([]Ordering{Sort("id", Asc)}).ToSql()
+	assert.Equal(t, sql, "SELECT a FROM b ORDER BY [id] ASC")
+
+	_, _, err := s.Select("a").From("b").Seek([]Ordering{Sort("id", Asc)}, 1, 2).ToSqlErr()
+	assert.NotNil(t, err)
+}
+
+func TestSelectSeekCursor(t *testing.T) {
+	s := createFakeConnection()
+	orders := []Ordering{Sort("p.created", Desc), Sort("p.id", Asc)}
+
+	type row struct {
+		Id      int64
+		Name    string
+		Created time.Time
+	}
+	tm := time.Date(2015, 6, 30, 21, 30, 15, 0, time.UTC)
+	cursor, err := NextCursor(orders, &row{Id: 5, Name: "x", Created: tm})
+	assert.NoError(t, err)
+
+	sql, args := s.Select("a").From("b p").SeekCursor(orders, cursor).ToSql()
+	assert.Equal(t, sql, "SELECT a FROM b p WHERE (([p.created] < ?) OR ([p.created] = ? AND [p.id] > ?))"+
+		" ORDER BY [p.created] DESC, [p.id] ASC")
+	assert.Equal(t, args, []interface{}{tm, tm, int64(5)})
+
+	cursor, err = NextCursor(orders[1:], uint8(3))
+	assert.NoError(t, err)
+	_, args = s.Select("a").From("b").SeekCursor(orders[1:], cursor).ToSql()
+	assert.Equal(t, args, []interface{}{uint64(3)})
+
+	sql, _ = s.Select("a").From("b").SeekCursor(orders, "").ToSql()
+	assert.Equal(t, sql, "SELECT a FROM b ORDER BY [p.created] DESC, [p.id] ASC")
+
+	_, _, err = s.Select("a").From("b").SeekCursor(orders, "!").ToSqlErr()
+	assert.Equal(t, err, ErrInvalidCursor)
+
+	type ptrRow struct {
+		Id      *int64
+		Created *time.Time
+	}
+	id := int64(7)
+	cursor, err = NextCursor(orders, ptrRow{&id, &tm})
+	assert.NoError(t, err)
+	_, args = s.Select("a").From("b").SeekCursor(orders, cursor).ToSql()
+	assert.Equal(t, args, []interface{}{tm, tm, int64(7)})
+
+	_, err = NextCursor(orders, ptrRow{nil, &tm})
+	assert.Equal(t, err, ErrNullCursorValue)
+	_, err = Cursor(nil)
+	assert.Equal(t, err, ErrNullCursorValue)
+}
+
+func TestSelectExprColumnsToSql(t *testing.T) {
+	s := createFakeConnection()
+
+	sql, args := s.Select("id", Expr("IF([score] > ?, ?, ?)", 50, "hi", "lo").As("band"),
+		Case().When("a", 1).Then("x").Else("y").As("c"),
+		Expr("?", s.Select("MAX(v)").From("w").Where("k = ?", 2)).As("m")).
+		With("t", s.Select("*").From("u").Where("z = ?", 0)).
+		From("t").Where("d = ?", 3).ToSql()
+	assert.Equal(t, sql, "WITH t AS (SELECT * FROM u WHERE ([z] = ?)) "+
+		"SELECT id, IF([score] > ?, ?, ?) AS [band], CASE WHEN [a] = ? THEN ? ELSE ? END AS [c], "+
+		"(SELECT MAX(v) FROM w WHERE ([k] = ?)) AS [m] FROM t WHERE ([d] = ?)")
+	assert.Equal(t, args, []interface{}{0, 50, "hi", "lo", 1, "x", "y", 2, 3})
+
+	b := s.Select(Expr("COUNT(*) > ?", 1).As("many")).From("t").Where("g = ?", 2)
+	assert.Equal(t, b.String(), "SELECT COUNT(*) > 1 AS `many` FROM t WHERE (`g` = 2)")
+
+	_, _, err := s.Select(1).From("t").ToSqlErr()
+	assert.Equal(t, err.Error(), "invalid column of type int, only a string, an Expr, or a CASE expression is allowed")
+
+	aliased := Expr("x").As("y")
+	builders := []queryBuilder{
+		s.Select("a").From("t").Where(aliased),
+		s.Select("a").From("t").OrderBy(aliased),
+		s.Select("a").From("t").Where(Case().When("b", 1).Then(aliased)),
+		s.Update("t").Set("a", aliased),
+		s.InsertInto("t").Columns("a").Values(aliased),
+	}
+	for _, b := range builders {
+		_, _, err := b.ToSqlErr()
+		assert.NotNil(t, err)
+	}
+	_, _, err = s.Update("t").Set("a", Case().When("b", 1).Then(1).Else(aliased)).ToSqlErr()
+	assert.Equal(t, err, errAliasedExpr)
+}
+
 func TestSelectVarieties(t *testing.T) {
 	s := createFakeConnection()
 
@@ -267,6 +600,29 @@ func TestSelectLoadStruct(t *testing.T) {
 	assert.Equal(t, err, ErrNotFound)
 }
 
+func TestSelectLoadPaginate(t *testing.T) {
+	s := createRealConnectionWithFixtures()
+
+	b := s.Select("id", "name", "email").From("dbr_people").OrderBy("id ASC")
+	n, err := b.Count()
+	assert.NoError(t, err)
+	assert.Equal(t, n, int64(2))
+
+	var people []*dbrPerson
+	total, err := b.Paginate(2, 1, &people)
+	assert.NoError(t, err)
+	assert.Equal(t, total, int64(2))
+	if assert.Equal(t, len(people), 1) {
+		assert.Equal(t, people[0].Name, "Dmitri")
+	}
+
+	people = nil
+	total, err = b.Paginate(3, 1, &people)
+	assert.NoError(t, err)
+	assert.Equal(t, total, int64(2))
+	assert.Equal(t, len(people), 0)
+}
+
 func TestSelectBySqlLoadStructs(t *testing.T) {
 	s := createRealConnectionWithFixtures()
 
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 )
 
 var destDummy interface{}
@@ -41,7 +42,7 @@ func calculateFieldMap(recordType reflect.Type, columns []string, requireAllColu
 					continue
 				}
 
-				name := fieldStruct.Tag.Get("db")
+				name, _ := parseTag(fieldStruct.Tag.Get("db"))
 				if name != "-" {
 					if name == "" {
 						name = NameMapping(fieldStruct.Name)
@@ -102,8 +103,34 @@ func valuesFor(recordType reflect.Type, record reflect.Value, columns []string)
 		} else {
 			field := record.FieldByIndex(fieldIndex)
 			values[i] = field.Interface()
+			if _, opts := parseTag(recordType.FieldByIndex(fieldIndex).Tag.Get("db")); hasTagOption(opts, "sensitive") {
+				values[i] = Secret(values[i])
+			}
 		}
 	}
 
 	return values, nil
 }
+
+// parseTag splits a db struct tag into the column name and the comma-separated
+// options, e.g. `db:"password,sensitive"`.
+func parseTag(tag string) (name, opts string) {
+	if i := strings.IndexByte(tag, ','); i != -1 {
+		return tag[:i], tag[i+1:]
+	}
+	return tag, ""
+}
+
+func hasTagOption(opts, opt string) bool {
+	for opts != "" {
+		var o string
+		o, opts = opts, ""
+		if i := strings.IndexByte(o, ','); i != -1 {
+			o, opts = o[:i], o[i+1:]
+		}
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package ql
+
+import "bytes"
+
+// Exists returns the EXISTS condition of the subquery to be used in Where
+// and Having.
+func Exists(sub *SelectBuilder) *expr {
+	return Expr("EXISTS ?", sub)
+}
+
+// NotExists returns the NOT EXISTS condition of the subquery to be used in
+// Where and Having.
+func NotExists(sub *SelectBuilder) *expr {
+	return Expr("NOT EXISTS ?", sub)
+}
+
+// asSubquery returns arg as a query builder if it is a SELECT statement
+// which can be used as a subquery.
+func asSubquery(arg interface{}) (queryBuilder, bool) {
+	switch q := arg.(type) {
+	case *SelectBuilder:
+		return q, true
+	case *CompoundBuilder:
+		return q, true
+	}
+	return nil, false
+}
+
+// expandSubqueries replaces the placeholders of the args which are subqueries
+// by the parenthesized SQL of the subqueries. The arguments of the subqueries
+// are merged into the returned args.
+func expandSubqueries(sql string, args []interface{}) (string, []interface{}, error) {
+	hasSubquery := false
+	for _, arg := range args {
+		if _, ok := asSubquery(arg); ok {
+			hasSubquery = true
+			break
+		}
+	}
+	if !hasSubquery {
+		return sql, args, nil
+	}
+
+	toks, err := lex(sql)
+	if err != nil {
+		return "", nil, err
+	}
+
+	buf := new(bytes.Buffer)
+	var newArgs []interface{}
+	n := 0
+	for _, t := range toks {
+		if t.kind == tokPlaceholder && n < len(args) {
+			if sub, ok := asSubquery(args[n]); ok {
+				subSql, subArgs, err := sub.ToSqlErr()
+				if err != nil {
+					return "", nil, err
+				}
+				buf.WriteString("(" + subSql + ")")
+				newArgs = append(newArgs, subArgs...)
+				n++
+				continue
+			}
+			newArgs = append(newArgs, args[n])
+			n++
+		}
+		buf.WriteString(t.text)
+	}
+	return buf.String(), append(newArgs, args[n:]...), nil
+}
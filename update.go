@@ -1,13 +1,18 @@
 package ql
 
-import "bytes"
+import (
+	"bytes"
+
+	"github.com/mibk/ql/query"
+)
 
 // UpdateBuilder contains the clauses for an UPDATE statement.
 type UpdateBuilder struct {
-	executor
+	modifier // Exec and methods for loading the returned rows
 
-	Table      string
-	SetClauses []*setClause
+	Table            string
+	SetClauses       []*setClause
+	ReturningColumns []string
 	*baseBuilder
 }
 
@@ -16,13 +21,53 @@ type setClause struct {
 	value  interface{}
 }
 
-func newUpdateBuilder(e EventReceiver, r runner, table string) *UpdateBuilder {
+func cloneSetClauses(clauses []*setClause) []*setClause {
+	var cs []*setClause
+	for _, c := range clauses {
+		c := *c
+		cs = append(cs, &c)
+	}
+	return cs
+}
+
+// insertedValue refers to the value of the column being inserted in an
+// upsert; see InsertBuilder.DoUpdate.
+type insertedValue string
+
+// writeSetClauses builds the assignments of a SET clause with placeholders and
+// adds the values to args.
+func writeSetClauses(w query.Writer, clauses []*setClause, args *[]interface{}) error {
+	for i, c := range clauses {
+		if i > 0 {
+			w.WriteString(", ")
+		}
+		D.EscapeIdent(w, c.column)
+		w.WriteString(" = ")
+		switch v := c.value.(type) {
+		case *expr:
+			sql, vals, err := expandSubqueries(v.Sql, v.Values)
+			if err != nil {
+				return err
+			}
+			w.WriteString(sql)
+			*args = append(*args, vals...)
+		case insertedValue:
+			D.EscapeInserted(w, string(v))
+		default:
+			w.WriteString("?")
+			*args = append(*args, c.value)
+		}
+	}
+	return nil
+}
+
+func newUpdateBuilder(c *Connection, r runner, table string) *UpdateBuilder {
 	b := &UpdateBuilder{
-		executor:    executor{EventReceiver: e, runner: r},
+		modifier:    newModifier(c, r, "ql.update"),
 		Table:       table,
-		baseBuilder: new(baseBuilder),
+		baseBuilder: newBaseBuilder(c),
 	}
-	b.executor.builder = b
+	b.loader.builder = b
 	return b
 }
 
@@ -36,35 +81,80 @@ func (tx *Tx) Update(table string) *UpdateBuilder {
 	return newUpdateBuilder(tx.Connection, tx.Tx, table)
 }
 
-// Set appends a column/value pair for the statement.
+// With adds a common table expression named name (which may include a list
+// of columns, e.g. "t(a, b)") to the WITH clause of the statement.
+func (b *UpdateBuilder) With(name string, sub *SelectBuilder) *UpdateBuilder {
+	b.with(name, sub, false)
+	return b
+}
+
+// WithRecursive adds a recursive common table expression to the WITH clause
+// of the statement. The sub is usually a UNION ALL of the initial SELECT and
+// the SELECT referencing name. See With.
+func (b *UpdateBuilder) WithRecursive(name string, sub *CompoundBuilder) *UpdateBuilder {
+	b.with(name, sub, true)
+	return b
+}
+
+// Set appends a column/value pair for the statement. The value can be an Expr
+// or a CASE expression.
 func (b *UpdateBuilder) Set(column string, value interface{}) *UpdateBuilder {
+	value = b.value(value)
 	b.SetClauses = append(b.SetClauses, &setClause{column: column, value: value})
 	return b
 }
 
 // SetMap appends the elements of the map as column/value pairs for the statement.
+// The pairs are sorted by the columns.
 func (b *UpdateBuilder) SetMap(clauses map[string]interface{}) *UpdateBuilder {
-	for col, val := range clauses {
-		b = b.Set(col, val)
+	for _, col := range sortedKeys(clauses) {
+		b = b.Set(col, clauses[col])
 	}
 	return b
 }
 
+// Join appends a JOIN of the table (aliased as alias if not empty) to the
+// statement. The ON condition is a string with args or an And map, as in Where.
+func (b *UpdateBuilder) Join(table, alias string, onExprOrMap interface{}, args ...interface{}) *UpdateBuilder {
+	b.join("JOIN", table, alias, onExprOrMap, args)
+	return b
+}
+
+// LeftJoin appends a LEFT JOIN to the statement. See Join.
+func (b *UpdateBuilder) LeftJoin(table, alias string, onExprOrMap interface{}, args ...interface{}) *UpdateBuilder {
+	b.join("LEFT JOIN", table, alias, onExprOrMap, args)
+	return b
+}
+
+// RightJoin appends a RIGHT JOIN to the statement. See Join.
+func (b *UpdateBuilder) RightJoin(table, alias string, onExprOrMap interface{}, args ...interface{}) *UpdateBuilder {
+	b.join("RIGHT JOIN", table, alias, onExprOrMap, args)
+	return b
+}
+
+// CrossJoin appends a CROSS JOIN of the table (aliased as alias if not empty)
+// to the statement.
+func (b *UpdateBuilder) CrossJoin(table, alias string) *UpdateBuilder {
+	b.join("CROSS JOIN", table, alias, nil, nil)
+	return b
+}
+
 // Where appends a WHERE clause to the statement.
 func (b *UpdateBuilder) Where(whereSqlOrMap interface{}, args ...interface{}) *UpdateBuilder {
 	b.where(whereSqlOrMap, args...)
 	return b
 }
 
-// OrderBy appends a column to ORDER the statement by.
-func (b *UpdateBuilder) OrderBy(expr string) *UpdateBuilder {
+// OrderBy appends a column, or an Expr or a CASE expression, to ORDER the
+// statement by.
+func (b *UpdateBuilder) OrderBy(expr interface{}) *UpdateBuilder {
 	b.orderBy(expr)
 	return b
 }
 
-// Order accepts By map of columns and directions to ORDER the statement by.
-func (b *UpdateBuilder) Order(by By) *UpdateBuilder {
-	b.order(by)
+// Order appends the orderings (Asc, Desc, or a By map) to ORDER the statement by.
+func (b *UpdateBuilder) Order(orders ...Orderer) *UpdateBuilder {
+	b.order(orders)
 	return b
 }
 
@@ -80,42 +170,74 @@ func (b *UpdateBuilder) Offset(offset uint64) *UpdateBuilder {
 	return b
 }
 
+// Returning sets the columns (or expressions) of the affected rows returned
+// by the statement, which can be loaded using All or One. It is supported by
+// dialect.Postgres only.
+func (b *UpdateBuilder) Returning(columns ...string) *UpdateBuilder {
+	b.ReturningColumns = columns
+	return b
+}
+
+// Clone returns a deep copy of the builder, which can be modified without
+// affecting b. Subqueries are shared.
+func (b *UpdateBuilder) Clone() *UpdateBuilder {
+	c := *b
+	c.SetClauses = cloneSetClauses(b.SetClauses)
+	c.ReturningColumns = cloneStrings(b.ReturningColumns)
+	c.baseBuilder = b.baseBuilder.clone()
+	c.loader.builder = &c
+	return &c
+}
+
 // ToSql serialized the UpdateBuilder to a SQL string. It returns the string with
-// placeholders and a slice of query arguments.
+// placeholders and a slice of query arguments. It returns an empty string if
+// the statement cannot be built; see ToSqlErr.
 func (b *UpdateBuilder) ToSql() (string, []interface{}) {
+	sql, args, _ := b.ToSqlErr()
+	return sql, args
+}
+
+// ToSqlErr is like ToSql, but it also returns the first error that occurred
+// while building the statement.
+func (b *UpdateBuilder) ToSqlErr() (string, []interface{}, error) {
+	if b.err != nil {
+		return "", nil, b.err
+	}
 	if len(b.Table) == 0 {
-		panic("no table specified")
+		return "", nil, ErrNoTable
 	}
 	if len(b.SetClauses) == 0 {
-		panic("no set clauses specified")
+		return "", nil, ErrNoSetClauses
 	}
 
 	sql := new(bytes.Buffer)
 	var args []interface{}
 
+	if err := b.buildWith(sql, &args); err != nil {
+		return "", nil, err
+	}
 	sql.WriteString("UPDATE ")
 	sql.WriteString(b.Table)
+	if err := b.buildJoins(sql, &args); err != nil {
+		return "", nil, err
+	}
 	sql.WriteString(" SET ")
 
-	// Build SET clause SQL with placeholders and add values to args
-	for i, c := range b.SetClauses {
-		if i > 0 {
-			sql.WriteString(", ")
-		}
-		D.EscapeIdent(sql, c.column)
-		if e, ok := c.value.(*expr); ok {
-			sql.WriteString(" = ")
-			sql.WriteString(e.Sql)
-			args = append(args, e.Values...)
-		} else {
-			sql.WriteString(" = ?")
-			args = append(args, c.value)
-		}
+	if err := writeSetClauses(sql, b.SetClauses, &args); err != nil {
+		return "", nil, err
 	}
 
-	b.buildWhere(sql, &args)
-	b.buildOrder(sql)
+	if err := b.buildWhere(sql, &args); err != nil {
+		return "", nil, err
+	}
+	if err := b.buildOrder(sql, &args); err != nil {
+		return "", nil, err
+	}
 	b.buildLimitAndOffset(sql)
 
-	return sql.String(), args
+	if err := buildReturning(sql, query.UpdateStmt, b.ReturningColumns); err != nil {
+		return "", nil, err
+	}
+
+	return sql.String(), args, nil
 }
@@ -3,6 +3,7 @@ package ql
 import (
 	"testing"
 
+	"github.com/mibk/ql/dialect"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -49,12 +50,8 @@ func TestUpdateSetMapToSql(t *testing.T) {
 
 	sql, args := s.Update("a").SetMap(map[string]interface{}{"b": 1, "c": 2}).Where("id = ?", 1).ToSql()
 
-	if sql == "UPDATE a SET `b` = ?, `c` = ? WHERE ([id] = ?)" {
-		assert.Equal(t, args, []interface{}{1, 2, 1})
-	} else {
-		assert.Equal(t, sql, "UPDATE a SET `c` = ?, `b` = ? WHERE ([id] = ?)")
-		assert.Equal(t, args, []interface{}{2, 1, 1})
-	}
+	assert.Equal(t, sql, "UPDATE a SET `b` = ?, `c` = ? WHERE ([id] = ?)")
+	assert.Equal(t, args, []interface{}{1, 2, 1})
 }
 
 func TestUpdateSetExprToSql(t *testing.T) {
@@ -129,3 +126,58 @@ func TestUpdateReal(t *testing.T) {
 	assert.Equal(t, person.Email.Valid, true)
 	assert.Equal(t, person.Email.String, "barack@whitehouse.gov")
 }
+
+func TestUpdateJoinToSql(t *testing.T) {
+	s := createFakeConnection()
+
+	sql, args := s.Update("user u").Join("team", "t", "t.id = u.team_id AND t.active = ?", true).
+		Set("u.rank", 1).Where("t.name", "core").ToSql()
+
+	assert.Equal(t, sql, "UPDATE user u JOIN team `t` ON (t.id = u.team_id AND t.active = ?) SET `u`.`rank` = ? WHERE ([t.name] = ?)")
+	assert.Equal(t, args, []interface{}{true, 1, "core"})
+}
+
+func TestUpdateReturningToSql(t *testing.T) {
+	s := createFakeConnection()
+	defer func(d Dialect) { D = d }(D)
+	D = dialect.Postgres{}
+
+	sql, args := s.Update("a").Set("b", 1).Where("id", 5).Returning("id", "[b]").ToSql()
+	assert.Equal(t, sql, `UPDATE a SET "b" = ? WHERE ([id] = ?) RETURNING id, [b]`)
+	assert.Equal(t, args, []interface{}{1, 5})
+
+	str := s.Update("a").Set("b", 1).Where("id", 5).Returning("id", "[b]").String()
+	assert.Equal(t, str, `UPDATE a SET "b" = 1 WHERE ("id" = 5) RETURNING id, "b"`)
+
+	D = dialect.MariaDB{}
+	_, _, err := s.Update("a").Set("b", 1).Returning("id").ToSqlErr()
+	assert.Equal(t, err, &dialect.UnsupportedError{Dialect: "MariaDB", Feature: "UPDATE ... RETURNING"})
+}
+
+func TestModifierEvents(t *testing.T) {
+	s := createFakeConnection()
+
+	// the EventReceiver is embedded only once, so its methods are promoted
+	var e EventReceiver = s.Update("a")
+	assert.NotNil(t, e)
+
+	assert.Equal(t, s.InsertInto("a").event(".load_all.query"), "ql.insert.load_all.query")
+	assert.Equal(t, s.Update("a").event(""), "ql.update")
+	assert.Equal(t, s.DeleteFrom("a").event(""), "ql.delete")
+	assert.Equal(t, s.Select("a").event(""), "dbr.select")
+}
+
+func TestUpdateClone(t *testing.T) {
+	s := createFakeConnection()
+
+	b := s.Update("a").Set("b", 1).Where("c = ?", 2)
+	c := b.Clone().Set("d", 3).Where("e = ?", 4)
+
+	sql, args := b.ToSql()
+	assert.Equal(t, sql, "UPDATE a SET `b` = ? WHERE ([c] = ?)")
+	assert.Equal(t, args, []interface{}{1, 2})
+
+	sql, args = c.ToSql()
+	assert.Equal(t, sql, "UPDATE a SET `b` = ?, `d` = ? WHERE ([c] = ?) AND ([e] = ?)")
+	assert.Equal(t, args, []interface{}{1, 3, 2, 4})
+}
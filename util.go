@@ -1,5 +1,10 @@
 package ql
 
+import (
+	"reflect"
+	"sort"
+)
+
 // NameMapping is the routine to use when mapping column names to struct properties
 var NameMapping = camelCaseToSnakeCase
 
@@ -21,3 +26,24 @@ func camelCaseToSnakeCase(name string) string {
 
 	return string(newstr)
 }
+
+// sortedKeys returns the sorted keys of a map with string keys.
+func sortedKeys(m interface{}) []string {
+	keys := reflect.ValueOf(m).MapKeys()
+	strs := make([]string, len(keys))
+	for i, k := range keys {
+		strs[i] = k.String()
+	}
+	sort.Strings(strs)
+	return strs
+}
+
+// cloneStrings returns a copy of s.
+func cloneStrings(s []string) []string {
+	return append([]string(nil), s...)
+}
+
+// cloneValues returns a copy of vals.
+func cloneValues(vals []interface{}) []interface{} {
+	return append([]interface{}(nil), vals...)
+}
@@ -1,36 +1,216 @@
 package ql
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"reflect"
 	"regexp"
+	"strings"
 
 	"github.com/mibk/ql/query"
 )
 
 // And is a map column -> value pairs which must be matched in a query.
+// The conditions are sorted by the columns.
 type And map[string]interface{}
 
+// Or is a map column -> value pairs of which at least one must be matched
+// in a query. The pairs are handled the same way as in And.
+type Or map[string]interface{}
+
+// AndList is like And, but the conditions keep their order. It consists of
+// alternating column expressions and values. Example:
+//
+//	b.Where(ql.AndList{"name", "Igor", "age >", 50})
+type AndList []interface{}
+
+// OrList is like Or, but the conditions keep their order. See AndList.
+type OrList []interface{}
+
+// pairs returns the column -> value pairs of an And or an Or map, or an
+// AndList or an OrList, sorted by the columns in the case of the maps.
+func pairs(exprOrMap interface{}) (exprs []string, args [][]interface{}, err error) {
+	switch c := exprOrMap.(type) {
+	case And:
+		for _, ex := range sortedKeys(c) {
+			exprs = append(exprs, ex)
+			args = append(args, []interface{}{c[ex]})
+		}
+	case Or:
+		for _, ex := range sortedKeys(c) {
+			exprs = append(exprs, ex)
+			args = append(args, []interface{}{c[ex]})
+		}
+	case AndList:
+		return listPairs(c)
+	case OrList:
+		return listPairs(c)
+	}
+	return exprs, args, nil
+}
+
+func listPairs(list []interface{}) (exprs []string, args [][]interface{}, err error) {
+	if len(list)%2 != 0 {
+		return nil, nil, errors.New("odd number of elements in a list of conditions")
+	}
+	for i := 0; i < len(list); i += 2 {
+		ex, ok := list[i].(string)
+		if !ok {
+			return nil, nil, errors.New("expression in a list of conditions must be a string")
+		}
+		exprs = append(exprs, ex)
+		args = append(args, []interface{}{list[i+1]})
+	}
+	return exprs, args, nil
+}
+
+type condGroup struct {
+	Op    string // AND or OR
+	Conds []interface{}
+}
+
+type notCond struct {
+	Cond interface{}
+	Args []interface{}
+}
+
+// AllOf returns a condition which is true if all the conds are true. A cond
+// can be anything accepted by Where on its own, i.e. a string, an And or an Or
+// map, an Expr, or another condition returned by AllOf, AnyOf, or Not.
+// Use Expr for expressions with arguments.
+func AllOf(conds ...interface{}) *condGroup {
+	return &condGroup{Op: "AND", Conds: conds}
+}
+
+// AnyOf returns a condition which is true if at least one of the conds is
+// true. See AllOf.
+func AnyOf(conds ...interface{}) *condGroup {
+	return &condGroup{Op: "OR", Conds: conds}
+}
+
+// Not returns the negation of the condition given as to Where, e.g.
+//
+//	b.Where(ql.Not("age >", 18))
+//	b.Where(ql.Not(ql.Or{"a": 1, "b": nil}))
+func Not(exprOrMap interface{}, args ...interface{}) *notCond {
+	return &notCond{Cond: exprOrMap, Args: args}
+}
+
 type whereFragment struct {
 	Condition string
 	Values    []interface{}
 }
 
-type addFragmentFn func(expr string, args ...interface{})
+func cloneFragments(fs []*whereFragment) []*whereFragment {
+	var cs []*whereFragment
+	for _, f := range fs {
+		cs = append(cs, &whereFragment{Condition: f.Condition, Values: cloneValues(f.Values)})
+	}
+	return cs
+}
+
+// conditions returns the fragments for the condition as accepted by Where.
+// Entries of an And map result in separate fragments. An invalid condition
+// is recorded as the error of the builder.
+func (b *baseBuilder) conditions(exprOrMap interface{}, args []interface{}) []*whereFragment {
+	switch exprOrMap.(type) {
+	case And, AndList:
+		if len(args) > 0 {
+			b.fail(errors.New("args are not expected when passing an And map"))
+			return nil
+		}
+		var fragments []*whereFragment
+		exprs, exprArgs, err := pairs(exprOrMap)
+		if err != nil {
+			b.fail(err)
+			return nil
+		}
+		for i, ex := range exprs {
+			cond, vals := b.compileCond(ex, exprArgs[i])
+			fragments = append(fragments, &whereFragment{cond, vals})
+		}
+		return fragments
+	}
+	cond, vals := b.compileCond(exprOrMap, args)
+	return []*whereFragment{{cond, vals}}
+}
 
-func handleExprType(exprOrMap interface{}, args []interface{}, fn addFragmentFn) {
-	switch v := exprOrMap.(type) {
+// compileCond returns the SQL and the arguments of the condition as accepted
+// by Where.
+func (b *baseBuilder) compileCond(exprOrMap interface{}, args []interface{}) (string, []interface{}) {
+	switch c := exprOrMap.(type) {
 	case string:
-		fn(v, args...)
-	case And:
+		if strings.TrimSpace(c) == "" {
+			b.fail(errors.New("empty condition expression"))
+			return "1=1", nil
+		}
+		b.checkSafe(c)
+		return handleShortNotation(c, args)
+	case expression:
 		if len(args) > 0 {
-			panic("args are not expected when passing an And map")
+			b.fail(errors.New("args are not expected when passing an Expr"))
 		}
-		for ex, arg := range v {
-			fn(ex, arg)
+		e := b.expr(c)
+		return e.Sql, e.Values
+	case *notCond:
+		if len(args) > 0 {
+			b.fail(errors.New("args are not expected when passing a Not condition"))
 		}
+		sql, vals := b.compileCond(c.Cond, c.Args)
+		return "NOT (" + sql + ")", vals
+	}
+
+	var op string
+	var conds []interface{}
+	var condArgs [][]interface{}
+	switch c := exprOrMap.(type) {
+	case And, AndList, Or, OrList:
+		op = "AND"
+		switch c.(type) {
+		case Or, OrList:
+			op = "OR"
+		}
+		exprs, exprArgs, err := pairs(c)
+		if err != nil {
+			b.fail(err)
+			return "1=1", nil
+		}
+		for _, ex := range exprs {
+			conds = append(conds, ex)
+		}
+		condArgs = exprArgs
+	case *condGroup:
+		op, conds = c.Op, c.Conds
+		condArgs = make([][]interface{}, len(conds))
 	default:
-		panic("invalid argument passed to Where, only a string or an And map is allowed")
+		b.fail(fmt.Errorf("invalid argument of type %T passed to Where, only a string, an And or an Or map or list, an Expr, or a condition is allowed", exprOrMap))
+		return "1=1", nil
+	}
+	if len(args) > 0 {
+		b.fail(errors.New("args are not expected when passing a map or a group of conditions"))
+	}
+
+	if len(conds) == 0 {
+		if op == "OR" {
+			return "1=0", nil
+		}
+		return "1=1", nil
+	}
+	if len(conds) == 1 {
+		return b.compileCond(conds[0], condArgs[0])
+	}
+	sql := new(bytes.Buffer)
+	var vals []interface{}
+	for i, cond := range conds {
+		if i > 0 {
+			sql.WriteString(" " + op + " ")
+		}
+		s, v := b.compileCond(cond, condArgs[i])
+		sql.WriteString("(" + s + ")")
+		vals = append(vals, v...)
 	}
+	return sql.String(), vals
 }
 
 var shortNotation = regexp.MustCompile(`^\s*([a-zA-Z._]+)\s*([a-zA-Z=<>!]+)?\s*\??\s*$`)
@@ -45,7 +225,12 @@ func handleShortNotation(expr string, args []interface{}) (string, []interface{}
 			expr = "[" + col + "]"
 
 			arg := args[0]
-			if arg == nil {
+			if s, ok := arg.(secret); ok {
+				arg = s.v
+			}
+			if _, ok := asSubquery(arg); ok && m[2] == "" {
+				expr += " IN ?"
+			} else if arg == nil {
 				expr += " IS NULL"
 				args = args[:0]
 			} else {
@@ -71,19 +256,17 @@ func handleShortNotation(expr string, args []interface{}) (string, []interface{}
 }
 
 // Invariant: only called when len(fragments) > 0.
-func writeWhereFragmentsToSql(w query.Writer, fragments []*whereFragment, args *[]interface{}) {
-	anyConditions := false
-	for _, f := range fragments {
-		if f.Condition == "" {
-			panic("invalid condition expression")
-		}
-		if anyConditions {
+func writeWhereFragmentsToSql(w query.Writer, fragments []*whereFragment, args *[]interface{}) error {
+	for i, f := range fragments {
+		if i > 0 {
 			w.WriteString(" AND ")
 		}
-		anyConditions = true
-		w.WriteString("(" + f.Condition + ")")
-		if len(f.Values) > 0 {
-			*args = append(*args, f.Values...)
+		cond, vals, err := expandSubqueries(f.Condition, f.Values)
+		if err != nil {
+			return err
 		}
+		w.WriteString("(" + cond + ")")
+		*args = append(*args, vals...)
 	}
+	return nil
 }
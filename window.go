@@ -0,0 +1,102 @@
+package ql
+
+import "bytes"
+
+// WindowSpec is a window specification used in the OVER and WINDOW clauses.
+type WindowSpec struct {
+	Ref          string // name of the window being refined
+	PartitionBys []string
+	OrderBys     []string
+	FrameClause  string
+}
+
+// Window returns a new, empty window specification.
+func Window() *WindowSpec {
+	return new(WindowSpec)
+}
+
+// WindowRef returns a window specification referring to the window named
+// name, which is defined by SelectBuilder.Window. The specification can be
+// further refined, e.g. by OrderBy.
+func WindowRef(name string) *WindowSpec {
+	return &WindowSpec{Ref: name}
+}
+
+// PartitionBy appends columns to PARTITION the window by.
+func (w *WindowSpec) PartitionBy(cols ...string) *WindowSpec {
+	for _, c := range cols {
+		w.PartitionBys = append(w.PartitionBys, "["+c+"]")
+	}
+	return w
+}
+
+// OrderBy appends an expression to ORDER the window by.
+func (w *WindowSpec) OrderBy(expr string) *WindowSpec {
+	w.OrderBys = append(w.OrderBys, expr)
+	return w
+}
+
+// Order appends the orderings (Asc, Desc, or a By map) to ORDER the window by.
+func (w *WindowSpec) Order(orders ...Orderer) *WindowSpec {
+	w.OrderBys = append(w.OrderBys, orderExprs(orders)...)
+	return w
+}
+
+// Frame sets the frame clause of the window, e.g.
+// "ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW".
+func (w *WindowSpec) Frame(frame string) *WindowSpec {
+	w.FrameClause = frame
+	return w
+}
+
+// sql returns the SQL of the specification as used after OVER.
+func (w *WindowSpec) sql() string {
+	if w.Ref != "" && len(w.PartitionBys) == 0 && len(w.OrderBys) == 0 && w.FrameClause == "" {
+		return "[" + w.Ref + "]"
+	}
+
+	buf := new(bytes.Buffer)
+	buf.WriteRune('(')
+	sep := ""
+	if w.Ref != "" {
+		buf.WriteString("[" + w.Ref + "]")
+		sep = " "
+	}
+	if len(w.PartitionBys) > 0 {
+		buf.WriteString(sep + "PARTITION BY ")
+		writeList(buf, w.PartitionBys)
+		sep = " "
+	}
+	if len(w.OrderBys) > 0 {
+		buf.WriteString(sep + "ORDER BY ")
+		writeList(buf, w.OrderBys)
+		sep = " "
+	}
+	if w.FrameClause != "" {
+		buf.WriteString(sep + w.FrameClause)
+	}
+	buf.WriteRune(')')
+	return buf.String()
+}
+
+// clone returns a copy of w.
+func (w *WindowSpec) clone() *WindowSpec {
+	c := *w
+	c.PartitionBys = cloneStrings(w.PartitionBys)
+	c.OrderBys = cloneStrings(w.OrderBys)
+	return &c
+}
+
+func writeList(buf *bytes.Buffer, list []string) {
+	for i, s := range list {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(s)
+	}
+}
+
+type namedWindow struct {
+	Name string
+	Spec *WindowSpec
+}